@@ -0,0 +1,103 @@
+package enfl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ConfigDirSource returns a Source reading directories that hold one file per key, the
+// layout Kubernetes uses when mounting ConfigMaps and Secrets as volumes. File names are
+// matched against environment variable names and a single trailing newline is trimmed from
+// the contents. Directories are listed highest priority first; missing ones are skipped
+func ConfigDirSource(dirs ...string) Source {
+	return &configDirSource{dirs: dirs}
+}
+
+// WithConfigDir adds one-file-per-key directories, highest priority first, below the
+// environment and above .env files. It may be called several times
+func WithConfigDir(dirs ...string) Option {
+	return func(l *Loader) {
+		l.configDirs = append(l.configDirs, dirs...)
+	}
+}
+
+type configDirSource struct {
+	dirs   []string
+	values map[string]string
+}
+
+func (s *configDirSource) envKeyed() {}
+
+func (s *configDirSource) Name() string {
+	return "config dir " + strings.Join(s.dirs, ", ")
+}
+
+func (s *configDirSource) Prepare() error {
+	s.values = make(map[string]string)
+	// Read the lowest priority directory first so higher ones overwrite it
+	for i := len(s.dirs) - 1; i >= 0; i-- {
+		values, err := readConfigDir(s.dirs[i])
+		if err != nil {
+			return err
+		}
+		for key, value := range values {
+			s.values[key] = value
+		}
+	}
+	return nil
+}
+
+// readConfigDir reads every regular file in dir, skipping the ..data style entries
+// Kubernetes uses for atomic updates. Symlinks are followed
+func readConfigDir(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config dir %s: %w", dir, err)
+	}
+
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config dir %s: %w", dir, err)
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config dir %s: %w", dir, err)
+		}
+		value := strings.TrimSuffix(string(data), "\n")
+		values[entry.Name()] = strings.TrimSuffix(value, "\r")
+	}
+	return values, nil
+}
+
+func (s *configDirSource) Lookup(key Key) (string, bool, error) {
+	if key.Env == "" {
+		return "", false, nil
+	}
+	value, ok := s.values[key.Env]
+	return value, ok, nil
+}
+
+func (s *configDirSource) Keys() []string {
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,102 @@
+package enfl
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeConfigMap lays out files the way Kubernetes mounts a ConfigMap volume:
+// KEY -> ..data/KEY, ..data -> ..<timestamp>
+func writeConfigMap(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	versioned := filepath.Join(dir, "..2024_01_01_00_00_00.000000001")
+	if err := os.Mkdir(versioned, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(versioned, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Base(versioned), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestConfigDirSource(t *testing.T) {
+	secrets := writeConfigMap(t, map[string]string{
+		"DB_PASSWORD": "s3cret\n",
+		"API_KEY":     "from-secret\r\n",
+	})
+	config := writeConfigMap(t, map[string]string{
+		"API_KEY": "from-config",
+		"PORT":    "9000\n",
+		"HOST":    "from-config",
+		"MOTD":    "line one\nline two\n\n",
+	})
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("HOST=from-dotenv\nNAME=from-dotenv\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type cfg struct {
+		Password string `env:"DB_PASSWORD"`
+		APIKey   string `env:"API_KEY"`
+		Port     int    `env:"PORT"`
+		Host     string `env:"HOST"`
+		Name     string `env:"NAME"`
+		Motd     string `env:"MOTD"`
+		Debug    bool   `env:"DEBUG" default:"true"`
+	}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron([]string{"PORT=7000"}),
+		WithEnvFiles(envFile),
+		WithConfigDir(secrets),
+		WithConfigDir(config, filepath.Join(t.TempDir(), "missing")),
+	)
+
+	var got cfg
+	if err := l.Load(&got); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	expected := cfg{
+		Password: "s3cret",
+		APIKey:   "from-secret",
+		Port:     7000,
+		Host:     "from-config",
+		Name:     "from-dotenv",
+		Motd:     "line one\nline two\n",
+		Debug:    true,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Load() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestConfigDirSourceKeys(t *testing.T) {
+	dir := writeConfigMap(t, map[string]string{"B": "2", "A": "1"})
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	s := ConfigDirSource(dir)
+	if err := s.(Preparer).Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if got := s.(Enumerator).Keys(); !reflect.DeepEqual(got, []string{"A", "B"}) {
+		t.Errorf("Keys() = %v, want [A B]", got)
+	}
+}
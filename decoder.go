@@ -0,0 +1,139 @@
+package enfl
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// decodeFunc converts a configuration value into a value of the type it is registered for
+type decodeFunc func(string) (interface{}, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[reflect.Type]decodeFunc{}
+)
+
+// RegisterDecoder makes every loader decode fields of type T with decode, including slice
+// elements, map values and flags. Decoders added with WithDecoder take precedence
+func RegisterDecoder[T any](decode func(string) (T, error)) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[t] = func(value string) (interface{}, error) {
+		return decode(value)
+	}
+}
+
+// WithDecoder decodes fields of type t with decode, which must return a value assignable to t.
+// A decoder for T also serves *T fields, which are only allocated when a value is set
+func WithDecoder(t reflect.Type, decode func(string) (interface{}, error)) Option {
+	return func(l *Loader) {
+		if l.decoders == nil {
+			l.decoders = make(map[reflect.Type]decodeFunc)
+		}
+		l.decoders[t] = decode
+	}
+}
+
+// builtinDecoders decode standard library types that have no text encoding of their own.
+// Types like net.IP, netip.Addr, *regexp.Regexp and slog.Level implement
+// encoding.TextUnmarshaler and need no entry
+var builtinDecoders = map[reflect.Type]decodeFunc{
+	reflect.TypeOf(url.URL{}):        decodeURL,
+	reflect.TypeOf(net.IPNet{}):      decodeIPNet,
+	reflect.TypeOf(&time.Location{}): decodeLocation,
+	reflect.TypeOf(os.FileMode(0)):   decodeFileMode,
+}
+
+// decoderFor returns the decoder for type t, looking at the loader, then the global
+// registry, then the built-in decoders
+func (l *Loader) decoderFor(t reflect.Type) (decodeFunc, bool) {
+	if decode, ok := l.decoders[t]; ok {
+		return decode, true
+	}
+
+	decodersMu.RLock()
+	decode, ok := decoders[t]
+	decodersMu.RUnlock()
+	if ok {
+		return decode, true
+	}
+
+	decode, ok = builtinDecoders[t]
+	return decode, ok
+}
+
+func decodeURL(value string) (interface{}, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return *u, nil
+}
+
+// decodeIPNet parses CIDR notation; the network keeps only the masked address, so
+// 10.0.0.5/24 is 10.0.0.0/24
+func decodeIPNet(value string) (interface{}, error) {
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, err
+	}
+	return *network, nil
+}
+
+// decodeLocation loads an IANA time zone such as Europe/Berlin, UTC or Local
+func decodeLocation(value string) (interface{}, error) {
+	return time.LoadLocation(value)
+}
+
+// decodeFileMode parses octal permissions written as 644, 0644 or 0o644
+func decodeFileMode(value string) (interface{}, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(value, "0o"), "0O")
+	mode, err := strconv.ParseUint(digits, 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file mode %q", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// isValueType reports whether fields of type t are decoded as a whole, by a registered
+// decoder or by the type itself, instead of being split or walked as nested structs
+func (l *Loader) isValueType(t reflect.Type) bool {
+	if _, ok := l.decoderFor(t); ok {
+		return true
+	}
+	return isTextType(t)
+}
+
+// setDecodedValue sets field with the decoder registered for its type. It reports false when
+// there is none
+func (l *Loader) setDecodedValue(field reflect.Value, value string) (bool, error) {
+	decode, ok := l.decoderFor(field.Type())
+	if !ok {
+		return false, nil
+	}
+
+	decoded, err := decode(value)
+	if err != nil {
+		return true, err
+	}
+	if decoded == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return true, nil
+	}
+
+	v := reflect.ValueOf(decoded)
+	if !v.Type().AssignableTo(field.Type()) {
+		return true, fmt.Errorf("decoder returned %s, want %s", v.Type(), field.Type())
+	}
+	field.Set(v)
+	return true, nil
+}
@@ -0,0 +1,335 @@
+package enfl
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// money is a struct type from a package we do not own, decoded by a registered decoder
+type money struct {
+	Units int64
+	Cents int64
+}
+
+func parseMoney(value string) (money, error) {
+	units, cents, _ := strings.Cut(value, ".")
+	u, err := strconv.ParseInt(units, 10, 64)
+	if err != nil {
+		return money{}, fmt.Errorf("invalid amount %q", value)
+	}
+	c, err := strconv.ParseInt("0"+cents, 10, 64)
+	if err != nil {
+		return money{}, fmt.Errorf("invalid amount %q", value)
+	}
+	return money{Units: u, Cents: c}, nil
+}
+
+// registerTestDecoder registers decode in the global registry until the test ends
+func registerTestDecoder[T any](t *testing.T, decode func(string) (T, error)) {
+	t.Helper()
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	decodersMu.RLock()
+	previous, registered := decoders[typ]
+	decodersMu.RUnlock()
+
+	RegisterDecoder(decode)
+	t.Cleanup(func() {
+		decodersMu.Lock()
+		defer decodersMu.Unlock()
+		if registered {
+			decoders[typ] = previous
+		} else {
+			delete(decoders, typ)
+		}
+	})
+}
+
+func TestDecoders(t *testing.T) {
+	registerTestDecoder(t, parseMoney)
+
+	type config struct {
+		Price   money            `env:"PRICE" flag:"price"`
+		Prices  []money          `env:"PRICES"`
+		Limit   *money           `env:"LIMIT"`
+		Tiers   map[string]money `env:"TIERS"`
+		Pattern *regexp.Regexp   `env:"PATTERN" flag:"pattern"`
+		Ports   []int            `env:"PORTS" flag:"ports"`
+	}
+
+	// Ports are written as a range, overriding the usual comma separated list
+	portRange := WithDecoder(reflect.TypeOf([]int(nil)), func(value string) (interface{}, error) {
+		from, to, ok := strings.Cut(value, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid port range %q", value)
+		}
+		lo, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, err
+		}
+		hi, err := strconv.Atoi(to)
+		if err != nil {
+			return nil, err
+		}
+		var ports []int
+		for p := lo; p <= hi; p++ {
+			ports = append(ports, p)
+		}
+		return ports, nil
+	})
+	pattern := WithDecoder(reflect.TypeOf(&regexp.Regexp{}), func(value string) (interface{}, error) {
+		return regexp.Compile("^" + value + "$")
+	})
+
+	tests := []struct {
+		name    string
+		env     []string
+		args    []string
+		check   func(t *testing.T, cfg config)
+		wantErr bool
+	}{
+		{
+			name: "Env",
+			env:  []string{"PRICE=9.99", "PRICES=1.5,2", "LIMIT=100", "TIERS=gold:10.5", "PATTERN=a+", "PORTS=8000-8002"},
+			check: func(t *testing.T, cfg config) {
+				if cfg.Price != (money{Units: 9, Cents: 99}) {
+					t.Errorf("Price = %+v, want 9.99", cfg.Price)
+				}
+				if !reflect.DeepEqual(cfg.Prices, []money{{Units: 1, Cents: 5}, {Units: 2}}) {
+					t.Errorf("Prices = %+v, want [1.5 2]", cfg.Prices)
+				}
+				if cfg.Limit == nil || *cfg.Limit != (money{Units: 100}) {
+					t.Errorf("Limit = %+v, want pointer to 100", cfg.Limit)
+				}
+				if !reflect.DeepEqual(cfg.Tiers, map[string]money{"gold": {Units: 10, Cents: 5}}) {
+					t.Errorf("Tiers = %+v, want gold:10.5", cfg.Tiers)
+				}
+				if cfg.Pattern == nil || cfg.Pattern.String() != "^a+$" {
+					t.Errorf("Pattern = %v, want ^a+$", cfg.Pattern)
+				}
+				if !reflect.DeepEqual(cfg.Ports, []int{8000, 8001, 8002}) {
+					t.Errorf("Ports = %v, want 8000-8002", cfg.Ports)
+				}
+			},
+		},
+		{
+			name: "Unset",
+			check: func(t *testing.T, cfg config) {
+				if cfg.Limit != nil || cfg.Pattern != nil {
+					t.Errorf("Load() = %+v, want nil pointers", cfg)
+				}
+			},
+		},
+		{
+			name: "Flags",
+			env:  []string{"PRICE=1"},
+			args: []string{"-price", "2.50", "-pattern", "b", "-ports", "1-2"},
+			check: func(t *testing.T, cfg config) {
+				if cfg.Price != (money{Units: 2, Cents: 50}) {
+					t.Errorf("Price = %+v, want 2.50", cfg.Price)
+				}
+				if cfg.Pattern == nil || cfg.Pattern.String() != "^b$" {
+					t.Errorf("Pattern = %v, want ^b$", cfg.Pattern)
+				}
+				if !reflect.DeepEqual(cfg.Ports, []int{1, 2}) {
+					t.Errorf("Ports = %v, want [1 2]", cfg.Ports)
+				}
+			},
+		},
+		{
+			name:    "Invalid Value",
+			env:     []string{"PRICE=free"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			l := NewLoader(WithFlagSet(flagSet), WithAutoLoadEnv(false), WithEnviron(tt.env), portRange, pattern)
+
+			var cfg config
+			if err := l.registerFlags(reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+				t.Fatalf("registerFlags() error = %v", err)
+			}
+			if err := flagSet.Parse(tt.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			err := l.Load(&cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, cfg)
+			}
+		})
+	}
+}
+
+func TestDecoderPrecedence(t *testing.T) {
+	// A loader decoder wins over the global registry
+	registerTestDecoder(t, parseMoney)
+	l := NewLoader(WithDecoder(reflect.TypeOf(money{}), func(value string) (interface{}, error) {
+		return money{Units: -1}, nil
+	}))
+	var m money
+	if err := l.setFieldValue(reflect.ValueOf(&m).Elem(), "5", "Price"); err != nil {
+		t.Fatalf("setFieldValue() error = %v", err)
+	}
+	if m != (money{Units: -1}) {
+		t.Errorf("setFieldValue() = %+v, want the loader decoder's result", m)
+	}
+}
+
+func TestDecoderWrongType(t *testing.T) {
+	l := NewLoader(WithDecoder(reflect.TypeOf(0), func(value string) (interface{}, error) {
+		return value, nil
+	}))
+	var n int
+	err := l.setFieldValue(reflect.ValueOf(&n).Elem(), "5", "Count")
+	if err == nil || !strings.Contains(err.Error(), "decoder returned string, want int") {
+		t.Errorf("setFieldValue() error = %v, want type mismatch", err)
+	}
+}
+
+func TestStandardTypes(t *testing.T) {
+	type config struct {
+		Started  time.Time      `env:"STARTED"`
+		Expires  time.Time      `env:"EXPIRES" flag:"expires" layout:"2006-01-02 15:04 MST"`
+		Created  *time.Time     `env:"CREATED" layout:"unixmilli"`
+		Unix     time.Time      `env:"UNIX"`
+		Endpoint *url.URL       `env:"ENDPOINT" flag:"endpoint"`
+		Proxy    url.URL        `env:"PROXY"`
+		IP       net.IP         `env:"IP" flag:"ip"`
+		Network  net.IPNet      `env:"NETWORK" flag:"network"`
+		Addr     netip.Addr     `env:"ADDR"`
+		Prefix   netip.Prefix   `env:"PREFIX"`
+		Listen   netip.AddrPort `env:"LISTEN" flag:"listen"`
+		Filter   *regexp.Regexp `env:"FILTER" flag:"filter"`
+		Zone     *time.Location `env:"ZONE" flag:"zone"`
+		Mode     os.FileMode    `env:"MODE" flag:"mode" default:"0644"`
+		Level    slog.Level     `env:"LEVEL" flag:"level" default:"info"`
+	}
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+	dotenv := "STARTED=2024-03-01T12:00:00Z\nEXPIRES=\"2024-03-01 13:30 UTC\"\nCREATED=1700000000000\nUNIX=1700000000\n" +
+		"ENDPOINT=https://api.example.com/v1?x=1\nPROXY=http://proxy:3128\nIP=10.0.0.1\nNETWORK=10.0.0.5/24\n" +
+		"ADDR=::1\nPREFIX=192.168.0.0/16\nLISTEN=0.0.0.0:8080\nFILTER=^a+$\nZONE=UTC\nMODE=0o600\nLEVEL=warn\n"
+	if err := os.WriteFile(envFile, []byte(dotenv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Dotenv", func(t *testing.T) {
+		l := NewLoader(WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)), WithAutoLoadEnv(false), WithEnviron(nil), WithEnvFiles(envFile))
+		var cfg config
+		if err := l.Load(&cfg); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		if want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC); !cfg.Started.Equal(want) {
+			t.Errorf("Started = %v, want %v", cfg.Started, want)
+		}
+		if want := time.Date(2024, 3, 1, 13, 30, 0, 0, time.UTC); !cfg.Expires.Equal(want) {
+			t.Errorf("Expires = %v, want %v", cfg.Expires, want)
+		}
+		if cfg.Created == nil || !cfg.Created.Equal(time.UnixMilli(1700000000000)) {
+			t.Errorf("Created = %v, want unix millis", cfg.Created)
+		}
+		if !cfg.Unix.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("Unix = %v, want unix seconds", cfg.Unix)
+		}
+		if cfg.Endpoint == nil || cfg.Endpoint.Host != "api.example.com" || cfg.Endpoint.Query().Get("x") != "1" {
+			t.Errorf("Endpoint = %v", cfg.Endpoint)
+		}
+		if cfg.Proxy.String() != "http://proxy:3128" {
+			t.Errorf("Proxy = %v", cfg.Proxy.String())
+		}
+		if !cfg.IP.Equal(net.ParseIP("10.0.0.1")) {
+			t.Errorf("IP = %v", cfg.IP)
+		}
+		if cfg.Network.String() != "10.0.0.0/24" {
+			t.Errorf("Network = %v, want 10.0.0.0/24", cfg.Network.String())
+		}
+		if cfg.Addr != netip.MustParseAddr("::1") || cfg.Prefix != netip.MustParsePrefix("192.168.0.0/16") || cfg.Listen != netip.MustParseAddrPort("0.0.0.0:8080") {
+			t.Errorf("Addr = %v, Prefix = %v, Listen = %v", cfg.Addr, cfg.Prefix, cfg.Listen)
+		}
+		if cfg.Filter == nil || !cfg.Filter.MatchString("aaa") || cfg.Filter.MatchString("b") {
+			t.Errorf("Filter = %v, want ^a+$", cfg.Filter)
+		}
+		if cfg.Zone != time.UTC {
+			t.Errorf("Zone = %v, want UTC", cfg.Zone)
+		}
+		if cfg.Mode != 0o600 {
+			t.Errorf("Mode = %v, want 0600", cfg.Mode)
+		}
+		if cfg.Level != slog.LevelWarn {
+			t.Errorf("Level = %v, want WARN", cfg.Level)
+		}
+	})
+
+	t.Run("Flags", func(t *testing.T) {
+		flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+		l := NewLoader(WithFlagSet(flagSet), WithAutoLoadEnv(false), WithEnviron(nil))
+
+		var cfg config
+		if err := l.registerFlags(reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+			t.Fatalf("registerFlags() error = %v", err)
+		}
+		args := []string{
+			"-expires", "2025-01-02 03:04 UTC", "-endpoint", "https://example.com", "-ip", "::1",
+			"-network", "fd00::/8", "-listen", "[::1]:443", "-filter", "x", "-zone", "UTC", "-mode", "755", "-level", "debug",
+		}
+		if err := flagSet.Parse(args); err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if err := l.Load(&cfg); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		if want := time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC); !cfg.Expires.Equal(want) {
+			t.Errorf("Expires = %v, want %v", cfg.Expires, want)
+		}
+		if cfg.Endpoint == nil || cfg.Endpoint.String() != "https://example.com" {
+			t.Errorf("Endpoint = %v", cfg.Endpoint)
+		}
+		if !cfg.IP.Equal(net.IPv6loopback) || cfg.Network.String() != "fd00::/8" || cfg.Listen != netip.MustParseAddrPort("[::1]:443") {
+			t.Errorf("IP = %v, Network = %v, Listen = %v", cfg.IP, cfg.Network.String(), cfg.Listen)
+		}
+		if cfg.Filter == nil || cfg.Filter.String() != "x" || cfg.Zone != time.UTC || cfg.Mode != 0o755 || cfg.Level != slog.LevelDebug {
+			t.Errorf("Filter = %v, Zone = %v, Mode = %v, Level = %v", cfg.Filter, cfg.Zone, cfg.Mode, cfg.Level)
+		}
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		l := NewLoader(WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)), WithAutoLoadEnv(false), WithEnviron(nil))
+		var cfg config
+		if err := l.Load(&cfg); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if cfg.Mode != 0o644 || cfg.Level != slog.LevelInfo || cfg.Endpoint != nil || cfg.Zone != nil || cfg.Created != nil {
+			t.Errorf("Load() = %+v, want defaults and nil pointers", cfg)
+		}
+	})
+
+	invalid := []string{"EXPIRES=tomorrow", "UNIX=soon", "CREATED=x", "NETWORK=10.0.0.1", "IP=nope", "ZONE=Mars/Olympus", "MODE=0999", "LEVEL=loud", "LISTEN=:x"}
+	for _, env := range invalid {
+		t.Run("Invalid "+env, func(t *testing.T) {
+			l := NewLoader(WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)), WithAutoLoadEnv(false), WithEnviron([]string{env}))
+			var cfg config
+			if err := l.Load(&cfg); err == nil {
+				t.Errorf("Load() error = nil, want invalid value")
+			}
+		})
+	}
+}
@@ -0,0 +1,453 @@
+package enfl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseError reports a syntax error in a configuration file
+type ParseError struct {
+	File   string // file name, empty when parsing a reader
+	Line   int    // 1-based line number
+	Column int    // 1-based column number, 0 for line oriented formats
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	if e.Column == 0 {
+		// Line oriented formats only know the line
+		if e.File != "" {
+			return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+		}
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// dotenvEntry is a single KEY=VALUE assignment read from a dotenv file
+type dotenvEntry struct {
+	key    string
+	value  string
+	file   string
+	line   int
+	expand bool // unquoted and double-quoted values expand ${VAR} references
+}
+
+// DotenvEntry is a single key/value pair of a dotenv file. When Expand is set, Value holds
+// ${VAR} references that are expanded on load and $$ stands for a literal dollar sign;
+// otherwise Value is taken literally
+type DotenvEntry struct {
+	Key    string
+	Value  string
+	Expand bool
+}
+
+// ParseDotenv parses dotenv content and returns its raw values; later assignments win.
+// Variable references are not expanded, see DotenvEntry
+func ParseDotenv(r io.Reader) (map[string]string, error) {
+	entries, err := ParseDotenvEntries(r)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		values[entry.Key] = entry.Value
+	}
+	return values, nil
+}
+
+// ParseDotenvEntries is like ParseDotenv but preserves the order in which keys first appear.
+// Expand is set for unquoted and double-quoted values holding variable references
+func ParseDotenvEntries(r io.Reader) ([]DotenvEntry, error) {
+	parsed, err := parseDotenv(r, "")
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]dotenvEntry, len(parsed))
+	var order []string
+	for _, entry := range parsed {
+		if _, exists := byKey[entry.key]; !exists {
+			order = append(order, entry.key)
+		}
+		byKey[entry.key] = entry
+	}
+
+	entries := make([]DotenvEntry, len(order))
+	for i, key := range order {
+		entries[i] = publicDotenvEntry(byKey[key])
+	}
+	return entries, nil
+}
+
+// publicDotenvEntry returns entry as a DotenvEntry, literal unless it holds variable references
+func publicDotenvEntry(entry dotenvEntry) DotenvEntry {
+	if entry.expand {
+		referenced := false
+		literal, err := expandVars(entry.value, func(string) (string, bool, error) {
+			referenced = true
+			return "", false, nil
+		})
+		if referenced || err != nil {
+			return DotenvEntry{Key: entry.key, Value: entry.value, Expand: true}
+		}
+		return DotenvEntry{Key: entry.key, Value: literal}
+	}
+	return DotenvEntry{Key: entry.key, Value: entry.value}
+}
+
+// MarshalDotenv writes entries in dotenv format so that ParseDotenvEntries returns them
+// unchanged. Literal values needing quotes are single-quoted, values to expand are
+// double-quoted with their references kept
+func MarshalDotenv(w io.Writer, entries []DotenvEntry) error {
+	bw := bufio.NewWriter(w)
+	for _, entry := range entries {
+		if !isDotenvKey(entry.Key) {
+			return fmt.Errorf("invalid dotenv key %q", entry.Key)
+		}
+		value := quoteDotenvLiteral(entry.Value)
+		if entry.Expand {
+			value = quoteDotenv(entry.Value)
+		}
+		if _, err := fmt.Fprintf(bw, "%s=%s\n", entry.Key, value); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// quoteDotenv leaves simple values bare and double-quotes everything else, escaping what
+// unquoteValue decodes; $ is kept so references still expand
+func quoteDotenv(value string) string {
+	if isBareDotenv(value) {
+		return value
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// quoteDotenvLiteral quotes a value that must not be expanded, in single quotes or backticks
+// when the value allows it and double-quoted with $ escaped otherwise
+func quoteDotenvLiteral(value string) string {
+	switch {
+	case isBareDotenv(value):
+		return value
+	case strings.ContainsRune(value, '\r'):
+		// Quotes without escapes cannot keep a carriage return
+	case !strings.ContainsRune(value, '\''):
+		return "'" + value + "'"
+	case !strings.ContainsRune(value, '`'):
+		return "`" + value + "`"
+	}
+	return quoteDotenv(strings.ReplaceAll(value, "$", "$$"))
+}
+
+// isBareDotenv reports whether value can be written without quotes
+func isBareDotenv(value string) bool {
+	for _, r := range value {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.,:/@%+=", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDotenvKey reports whether key can be read back by the parser
+func isDotenvKey(key string) bool {
+	p := &dotenvParser{src: []rune(key)}
+	return key != "" && p.parseKey() == key
+}
+
+const eof = -1
+
+// dotenvParser implements the dotenv grammar shared by docker compose and the Node/Ruby libraries:
+//
+//	# comment
+//	export KEY=value        # inline comment, needs whitespace before '#'
+//	KEY='literal $value'    # single quotes and backticks keep the value as is
+//	KEY="line\nbreak"       # double quotes understand \n \r \t \" \\ \$ and may span lines
+//	KEY=${HOME}/app         # unquoted and double-quoted values expand variables, see expandVars
+//	KEY=pa\$word            # \$ is a literal $ in unquoted and double-quoted values
+//	KEY=                    # empty value
+type dotenvParser struct {
+	file string
+	src  []rune
+	pos  int
+	line int
+	col  int
+}
+
+// parseDotenv reads all assignments from r, file is only used in error messages
+func parseDotenv(r io.Reader, file string) ([]dotenvEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &dotenvParser{
+		file: file,
+		src:  []rune(strings.ReplaceAll(string(data), "\r\n", "\n")),
+		line: 1,
+		col:  1,
+	}
+	return p.parse()
+}
+
+func (p *dotenvParser) parse() ([]dotenvEntry, error) {
+	var entries []dotenvEntry
+	for {
+		// Skip blank lines and indentation
+		for isSpace(p.peek()) || p.peek() == '\n' {
+			p.next()
+		}
+
+		switch p.peek() {
+		case eof:
+			return entries, nil
+		case '#':
+			p.skipLine()
+			continue
+		}
+
+		entry, err := p.parseEntry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func (p *dotenvParser) parseEntry() (dotenvEntry, error) {
+	entry := dotenvEntry{file: p.file, line: p.line}
+
+	key := p.parseKey()
+	// "export KEY=value" is accepted for compatibility with shell scripts
+	if key == "export" && isSpace(p.peek()) {
+		p.skipSpaces()
+		key = p.parseKey()
+	}
+	if key == "" {
+		return entry, p.errorf("invalid character %s at start of key", quoteRune(p.peek()))
+	}
+	entry.key = key
+
+	p.skipSpaces()
+	if p.peek() != '=' {
+		return entry, p.errorf("expected '=' after key %s, found %s", key, quoteRune(p.peek()))
+	}
+	p.next()
+	spaced := p.skipSpaces() > 0
+
+	var err error
+	switch quote := p.peek(); quote {
+	case '"', '\'', '`':
+		entry.value, err = p.parseQuoted()
+		entry.expand = quote == '"'
+	default:
+		entry.value = p.parseUnquoted(spaced)
+		entry.expand = true
+	}
+	return entry, err
+}
+
+// parseKey reads [A-Za-z_][A-Za-z0-9_.-]*
+func (p *dotenvParser) parseKey() string {
+	start := p.pos
+	for {
+		r := p.peek()
+		isStart := r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')
+		isRest := (r >= '0' && r <= '9') || r == '.' || r == '-'
+		if !isStart && !(isRest && p.pos > start) {
+			break
+		}
+		p.next()
+	}
+	return string(p.src[start:p.pos])
+}
+
+// parseQuoted reads a quoted value that may span multiple lines
+func (p *dotenvParser) parseQuoted() (string, error) {
+	line, col := p.line, p.col
+	quote := p.next()
+
+	var raw strings.Builder
+	raw.WriteRune(quote)
+	for {
+		r := p.next()
+		if r == eof {
+			return "", &ParseError{File: p.file, Line: line, Column: col, Msg: "unterminated quoted value"}
+		}
+		raw.WriteRune(r)
+		if r == '\\' && quote == '"' {
+			// Keep the escaped character so an escaped quote does not end the value
+			if escaped := p.next(); escaped != eof {
+				raw.WriteRune(escaped)
+			}
+			continue
+		}
+		if r == quote {
+			break
+		}
+	}
+
+	// Only whitespace or a comment may follow the closing quote
+	p.skipSpaces()
+	switch p.peek() {
+	case eof, '\n':
+	case '#':
+		p.skipLine()
+	default:
+		return "", p.errorf("unexpected character %s after quoted value", quoteRune(p.peek()))
+	}
+
+	return unquoteValue(raw.String()), nil
+}
+
+// parseUnquoted reads the rest of the line, stopping at a '#' preceded by whitespace
+func (p *dotenvParser) parseUnquoted(spaced bool) string {
+	var value strings.Builder
+	for {
+		r := p.peek()
+		if r == eof || r == '\n' {
+			break
+		}
+		if r == '#' && spaced {
+			p.skipLine()
+			break
+		}
+		spaced = isSpace(r)
+		p.next()
+		if r == '\\' && p.peek() == '$' {
+			// A literal $, written as $$ so it is not expanded
+			p.next()
+			value.WriteString("$$")
+			continue
+		}
+		value.WriteRune(r)
+	}
+	return strings.TrimSpace(value.String())
+}
+
+func (p *dotenvParser) peek() rune {
+	if p.pos >= len(p.src) {
+		return eof
+	}
+	return p.src[p.pos]
+}
+
+func (p *dotenvParser) next() rune {
+	r := p.peek()
+	if r == eof {
+		return r
+	}
+	p.pos++
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+	return r
+}
+
+// skipSpaces skips spaces and tabs and returns how many were skipped
+func (p *dotenvParser) skipSpaces() int {
+	n := 0
+	for isSpace(p.peek()) {
+		p.next()
+		n++
+	}
+	return n
+}
+
+func (p *dotenvParser) skipLine() {
+	for r := p.peek(); r != eof && r != '\n'; r = p.peek() {
+		p.next()
+	}
+}
+
+func (p *dotenvParser) errorf(format string, args ...interface{}) error {
+	return &ParseError{File: p.file, Line: p.line, Column: p.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+func quoteRune(r rune) string {
+	if r == eof {
+		return "end of file"
+	}
+	if r == '\n' {
+		return "end of line"
+	}
+	return fmt.Sprintf("%q", r)
+}
+
+// unquoteValue removes quotes from values and handles escape sequences
+func unquoteValue(value string) string {
+	if len(value) < 2 || value[0] != value[len(value)-1] {
+		return value
+	}
+
+	switch value[0] {
+	case '"':
+		// Handle escape sequences in a single pass so "\\n" stays a backslash followed by n
+		inner := value[1 : len(value)-1]
+		var b strings.Builder
+		for i := 0; i < len(inner); i++ {
+			if inner[i] != '\\' || i == len(inner)-1 {
+				b.WriteByte(inner[i])
+				continue
+			}
+			i++
+			switch inner[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(inner[i])
+			case '$':
+				// A literal $, written as $$ so it is not expanded
+				b.WriteString("$$")
+			default:
+				// Unknown escapes are kept verbatim
+				b.WriteByte('\\')
+				b.WriteByte(inner[i])
+			}
+		}
+		return b.String()
+	case '\'', '`':
+		// Single quotes and backticks have no escape sequences
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
@@ -0,0 +1,195 @@
+package enfl
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []dotenvEntry
+	}{
+		{
+			name:     "Simple",
+			input:    "KEY=value\nOTHER = spaced value \n",
+			expected: []dotenvEntry{{key: "KEY", value: "value", line: 1, expand: true}, {key: "OTHER", value: "spaced value", line: 2, expand: true}},
+		},
+		{
+			name:     "Export Prefix",
+			input:    "export KEY=value\nexport=keyword",
+			expected: []dotenvEntry{{key: "KEY", value: "value", line: 1, expand: true}, {key: "export", value: "keyword", line: 2, expand: true}},
+		},
+		{
+			name:     "Comments",
+			input:    "# header\nKEY=value # trailing\nURL=http://host/#anchor\nEMPTY= # nothing\n  # indented",
+			expected: []dotenvEntry{{key: "KEY", value: "value", line: 2, expand: true}, {key: "URL", value: "http://host/#anchor", line: 3, expand: true}, {key: "EMPTY", value: "", line: 4, expand: true}},
+		},
+		{
+			name:     "Quoted Values",
+			input:    `A="hello # not a comment" # comment` + "\n" + `B='single \n $raw'` + "\n" + "C=`back tick`",
+			expected: []dotenvEntry{{key: "A", value: "hello # not a comment", line: 1, expand: true}, {key: "B", value: `single \n $raw`, line: 2}, {key: "C", value: "back tick", line: 3}},
+		},
+		{
+			name:     "Escapes",
+			input:    `KEY="tab\there \"quoted\" back\\slash\\n new\nline"`,
+			expected: []dotenvEntry{{key: "KEY", value: "tab\there \"quoted\" back\\slash\\n new\nline", line: 1, expand: true}},
+		},
+		{
+			name:     "Multiline Double Quoted",
+			input:    "CERT=\"-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\"\nNEXT=1",
+			expected: []dotenvEntry{{key: "CERT", value: "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----", line: 1, expand: true}, {key: "NEXT", value: "1", line: 4, expand: true}},
+		},
+		{
+			name:     "CRLF Line Endings",
+			input:    "A=1\r\nB=\"x\r\ny\"\r\n",
+			expected: []dotenvEntry{{key: "A", value: "1", line: 1, expand: true}, {key: "B", value: "x\ny", line: 2, expand: true}},
+		},
+		{
+			name:     "Empty Value",
+			input:    "KEY=\nQUOTED=\"\"",
+			expected: []dotenvEntry{{key: "KEY", value: "", line: 1, expand: true}, {key: "QUOTED", value: "", line: 2, expand: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDotenv(strings.NewReader(tt.input), "test.env")
+			if err != nil {
+				t.Fatalf("parseDotenv() error = %v", err)
+			}
+			for i := range got {
+				got[i].file = ""
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseDotenv() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+	}{
+		{name: "Missing Equals", input: "A=1\nKEY value", line: 2, column: 5},
+		{name: "Invalid Key", input: "1KEY=value", line: 1, column: 1},
+		{name: "Unterminated Quote", input: "A=1\nB=\"open\nstill open", line: 2, column: 3},
+		{name: "Text After Quote", input: "KEY='value' extra", line: 1, column: 13},
+		{name: "Export Without Assignment", input: "export KEY", line: 1, column: 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseDotenv(strings.NewReader(tt.input), "test.env")
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("parseDotenv() error = %v, want *ParseError", err)
+			}
+			if perr.Line != tt.line || perr.Column != tt.column {
+				t.Errorf("parseDotenv() error at %d:%d, want %d:%d (%v)", perr.Line, perr.Column, tt.line, tt.column, err)
+			}
+		})
+	}
+}
+
+func TestMarshalDotenvRoundTrip(t *testing.T) {
+	entries := []DotenvEntry{
+		{Key: "SIMPLE", Value: "value"},
+		{Key: "URL", Value: "https://user@example.com:8443/path?q=1"},
+		{Key: "EMPTY", Value: ""},
+		{Key: "SPACES", Value: "  padded value  "},
+		{Key: "COMMENT", Value: "value # not a comment"},
+		{Key: "QUOTES", Value: `it's "quoted"`},
+		{Key: "BACKSLASH", Value: `C:\path\n`},
+		{Key: "DOLLAR", Value: "${HOME} and $$"},
+		{Key: "MIXED", Value: "it's `$5`"},
+		{Key: "REFERENCE", Value: "${HOME}/app and $$5", Expand: true},
+		{Key: "PEM", Value: "-----BEGIN KEY-----\nabc\r\n\tdef\n-----END KEY-----"},
+		{Key: "UNICODE", Value: "héllo wörld"},
+	}
+
+	var buf strings.Builder
+	if err := MarshalDotenv(&buf, entries); err != nil {
+		t.Fatalf("MarshalDotenv() error = %v", err)
+	}
+
+	got, err := ParseDotenvEntries(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("ParseDotenvEntries() error = %v\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("round trip = %+v, want %+v\n%s", got, entries, buf.String())
+	}
+
+	if err := MarshalDotenv(&buf, []DotenvEntry{{Key: "BAD KEY", Value: "x"}}); err == nil {
+		t.Error("MarshalDotenv() with invalid key should fail")
+	}
+}
+
+func TestParseDotenvOrderAndRawValues(t *testing.T) {
+	// References are returned unexpanded, whatever the process environment holds
+	t.Setenv("B", "from-env")
+	input := "B=2\nA=${B}0\nB=3\nC='${B}'\nD='pa$word'\nE=$$5\n"
+
+	entries, err := ParseDotenvEntries(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseDotenvEntries() error = %v", err)
+	}
+	expected := []DotenvEntry{
+		{Key: "B", Value: "3"},
+		{Key: "A", Value: "${B}0", Expand: true},
+		{Key: "C", Value: "${B}"},
+		{Key: "D", Value: "pa$word"},
+		{Key: "E", Value: "$5"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("ParseDotenvEntries() = %+v, want %+v", entries, expected)
+	}
+
+	values, err := ParseDotenv(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseDotenv() error = %v", err)
+	}
+	if values["A"] != "${B}0" || len(values) != 5 {
+		t.Errorf("ParseDotenv() = %v", values)
+	}
+}
+
+func TestMarshalDotenvKeepsReferences(t *testing.T) {
+	input := "HOST=db\nURL=${HOST}/x\nLITERAL='${HOST}'\n"
+	entries, err := ParseDotenvEntries(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseDotenvEntries() error = %v", err)
+	}
+	var buf strings.Builder
+	if err := MarshalDotenv(&buf, entries); err != nil {
+		t.Fatalf("MarshalDotenv() error = %v", err)
+	}
+
+	// The rewritten file loads to the same values as the original
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(buf.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := struct {
+		URL     string `env:"URL"`
+		Literal string `env:"LITERAL"`
+	}{}
+	l := NewLoader(WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)), WithAutoLoadEnv(false), WithEnviron(nil), WithEnvFiles(path))
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v\n%s", err, buf.String())
+	}
+	if cfg.URL != "db/x" || cfg.Literal != "${HOST}" {
+		t.Errorf("Load() = %+v, want references kept\n%s", cfg, buf.String())
+	}
+}
@@ -0,0 +1,194 @@
+package enfl
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// encryptedPrefix marks a .env value encrypted with AES-GCM: enc:v1:<base64 nonce|ciphertext>
+const encryptedPrefix = "enc:v1:"
+
+// WithDotenvKey sets the AES key (16, 24 or 32 bytes) used to decrypt enc:v1: values in .env files
+func WithDotenvKey(key []byte) Option {
+	return func(l *Loader) {
+		l.dotenvKey = key
+	}
+}
+
+// WithDotenvKeyFile reads the base64 encoded AES key from path when .env files hold encrypted values
+func WithDotenvKeyFile(path string) Option {
+	return func(l *Loader) {
+		l.keyFile = path
+	}
+}
+
+// WithDotenvKeyVar sets the environment variable holding the base64 encoded AES key,
+// DOTENV_ENCRYPTION_KEY by default. WithDotenvKey and WithDotenvKeyFile take precedence
+func WithDotenvKeyVar(name string) Option {
+	return func(l *Loader) {
+		l.keyVar = name
+	}
+}
+
+// decryptionKey returns the key for encrypted .env values
+func (l *Loader) decryptionKey() ([]byte, error) {
+	if l.dotenvKey != nil {
+		return l.dotenvKey, nil
+	}
+	if l.keyFile != "" {
+		data, err := os.ReadFile(l.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dotenv key: %w", err)
+		}
+		return decodeDotenvKey(string(data))
+	}
+	if encoded, ok := l.lookupEnv(l.keyVar); ok && encoded != "" {
+		return decodeDotenvKey(encoded)
+	}
+	return nil, fmt.Errorf("no dotenv key configured, set %s", l.keyVar)
+}
+
+func decodeDotenvKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid dotenv key: %w", err)
+	}
+	return key, nil
+}
+
+// decryptEntries decrypts the encrypted values among entries in place, reading the key
+// only when one is found. Decrypted values expand ${VAR} like unquoted ones
+func (l *Loader) decryptEntries(entries []dotenvEntry) error {
+	var key []byte
+	for i := range entries {
+		if !strings.HasPrefix(entries[i].value, encryptedPrefix) {
+			continue
+		}
+		if key == nil {
+			var err error
+			if key, err = l.decryptionKey(); err != nil {
+				return err
+			}
+		}
+		plaintext, err := decryptValue(entries[i].value, entries[i].key, key)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", entries[i].line, err)
+		}
+		entries[i].value = plaintext
+		entries[i].expand = true
+	}
+	return nil
+}
+
+// encryptValue seals plaintext with AES-GCM, using name as additional data so a value
+// cannot be moved to another key
+func encryptValue(plaintext, name string, key []byte) (string, error) {
+	aead, err := newDotenvCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(name))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptValue(value, name string, key []byte) (string, error) {
+	aead, err := newDotenvCipher(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value for %s", name)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: wrong key or corrupted value", name)
+	}
+	return string(plaintext), nil
+}
+
+func newDotenvCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dotenv key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptDotenv reads dotenv content from r and writes it to w with every value encrypted
+// under key. Values that are already encrypted are kept, so new plain entries can be added
+// to an encrypted file and the file encrypted again. ${VAR} references survive encryption
+// and are expanded after decryption; comments and blank lines are not preserved
+func EncryptDotenv(w io.Writer, r io.Reader, key []byte) error {
+	if _, err := newDotenvCipher(key); err != nil {
+		return err
+	}
+	return rewriteDotenv(w, r, func(entry dotenvEntry) (string, error) {
+		if strings.HasPrefix(entry.value, encryptedPrefix) {
+			return entry.value, nil
+		}
+		return encryptValue(expandableValue(entry), entry.key, key)
+	})
+}
+
+// DecryptDotenv reads dotenv content from r and writes it to w with every encrypted value
+// decrypted with key. Together with EncryptDotenv it rotates keys:
+//
+//	DecryptDotenv(&plain, encrypted, oldKey)
+//	EncryptDotenv(rotated, &plain, newKey)
+func DecryptDotenv(w io.Writer, r io.Reader, key []byte) error {
+	if _, err := newDotenvCipher(key); err != nil {
+		return err
+	}
+	return rewriteDotenv(w, r, func(entry dotenvEntry) (string, error) {
+		if !strings.HasPrefix(entry.value, encryptedPrefix) {
+			return quoteDotenv(expandableValue(entry)), nil
+		}
+		plaintext, err := decryptValue(entry.value, entry.key, key)
+		if err != nil {
+			return "", err
+		}
+		return quoteDotenv(plaintext), nil
+	})
+}
+
+// rewriteDotenv parses r without expanding it and writes every entry with the value returned by rewrite
+func rewriteDotenv(w io.Writer, r io.Reader, rewrite func(dotenvEntry) (string, error)) error {
+	entries, err := parseDotenv(r, "")
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, entry := range entries {
+		value, err := rewrite(entry)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", entry.line, err)
+		}
+		if _, err := fmt.Fprintf(bw, "%s=%s\n", entry.key, value); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// expandableValue returns the value of entry in the form expandVars reads, escaping $ in
+// literal values so they stay literal
+func expandableValue(entry dotenvEntry) string {
+	if entry.expand {
+		return entry.value
+	}
+	return strings.ReplaceAll(entry.value, "$", "$$")
+}
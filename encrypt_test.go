@@ -0,0 +1,178 @@
+package enfl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	testDotenvKey  = bytes.Repeat([]byte{1}, 32)
+	testRotatedKey = bytes.Repeat([]byte{2}, 32)
+)
+
+type encryptTestConfig struct {
+	Host     string `env:"HOST"`
+	Password string `env:"PASSWORD"`
+	Literal  string `env:"LITERAL"`
+	URL      string `env:"URL"`
+}
+
+func encryptString(t *testing.T, content string, key []byte) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := EncryptDotenv(&out, strings.NewReader(content), key); err != nil {
+		t.Fatalf("EncryptDotenv() error = %v", err)
+	}
+	return out.String()
+}
+
+func loadEncrypted(t *testing.T, opts ...Option) (encryptTestConfig, error) {
+	t.Helper()
+	opts = append([]Option{
+		WithFlagSet(flag.NewFlagSet(t.Name(), flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+	}, opts...)
+
+	var cfg encryptTestConfig
+	err := NewLoader(opts...).Load(&cfg)
+	return cfg, err
+}
+
+func TestEncryptedDotenv(t *testing.T) {
+	plain := "HOST=db.internal\nPASSWORD=\"p@ss word\"\nLITERAL='cost $5'\nURL=postgres://${HOST}/app\n"
+	encrypted := encryptString(t, plain, testDotenvKey)
+
+	for _, line := range strings.Split(strings.TrimSpace(encrypted), "\n") {
+		if _, value, _ := strings.Cut(line, "="); !strings.HasPrefix(value, "enc:v1:") {
+			t.Errorf("EncryptDotenv() line %q is not encrypted", line)
+		}
+	}
+	if again := encryptString(t, encrypted, testDotenvKey); again != encrypted {
+		t.Errorf("EncryptDotenv() re-encrypted existing values:\n%s", again)
+	}
+
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, ".env.enc")
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(envFile, []byte(encrypted), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(testDotenvKey)
+	if err := os.WriteFile(keyFile, []byte(encodedKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := encryptTestConfig{
+		Host:     "db.internal",
+		Password: "p@ss word",
+		Literal:  "cost $5",
+		URL:      "postgres://db.internal/app",
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Key Option", opts: []Option{WithEnviron(nil), WithDotenvKey(testDotenvKey)}},
+		{name: "Key File", opts: []Option{WithEnviron(nil), WithDotenvKeyFile(keyFile)}},
+		{name: "Key Variable", opts: []Option{WithEnviron([]string{"DOTENV_ENCRYPTION_KEY=" + encodedKey})}},
+		{name: "Custom Key Variable", opts: []Option{WithEnviron([]string{"SECRETS_KEY=" + encodedKey}), WithDotenvKeyVar("SECRETS_KEY")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadEncrypted(t, append(tt.opts, WithEnvFiles(envFile))...)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg != expected {
+				t.Errorf("Load() = %+v, want %+v", cfg, expected)
+			}
+		})
+	}
+}
+
+func TestEncryptedDotenvErrors(t *testing.T) {
+	encrypted := encryptString(t, "HOST=db\nPASSWORD=secret\n", testDotenvKey)
+	lines := strings.Split(strings.TrimSpace(encrypted), "\n")
+	_, hostValue, _ := strings.Cut(lines[0], "=")
+
+	tests := []struct {
+		name    string
+		content string
+		opts    []Option
+		wantErr string
+	}{
+		{name: "No Key", content: encrypted, opts: []Option{WithEnviron(nil)}, wantErr: "no dotenv key configured, set DOTENV_ENCRYPTION_KEY"},
+		{name: "Wrong Key", content: encrypted, opts: []Option{WithEnviron(nil), WithDotenvKey(testRotatedKey)}, wantErr: "failed to decrypt HOST"},
+		{name: "Moved Value", content: "PASSWORD=" + hostValue + "\n", opts: []Option{WithEnviron(nil), WithDotenvKey(testDotenvKey)}, wantErr: "failed to decrypt PASSWORD"},
+		{name: "Malformed", content: "HOST=enc:v1:%%%\n", opts: []Option{WithEnviron(nil), WithDotenvKey(testDotenvKey)}, wantErr: "malformed encrypted value for HOST"},
+		{name: "Bad Key Size", content: encrypted, opts: []Option{WithEnviron(nil), WithDotenvKey([]byte("short"))}, wantErr: "invalid dotenv key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envFile := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(envFile, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadEncrypted(t, append(tt.opts, WithEnvFiles(envFile))...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncryptedDotenvCascade(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".env.production":     "HOST=plain\nPASSWORD=plain\n",
+		".env.production.enc": encryptString(t, "PASSWORD=encrypted\n", testDotenvKey),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+
+	cfg, err := loadEncrypted(t, WithEnviron(nil), WithAutoLoadEnv(true), WithEnvironment("production"), WithDotenvKey(testDotenvKey))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Host != "plain" || cfg.Password != "encrypted" {
+		t.Errorf("Load() = %+v, want Host=plain and Password=encrypted", cfg)
+	}
+}
+
+func TestRotateDotenvKey(t *testing.T) {
+	encrypted := encryptString(t, "HOST=db\nLITERAL='a $b'\nURL=\"${HOST}\\n\"\n", testDotenvKey)
+
+	var plain bytes.Buffer
+	if err := DecryptDotenv(&plain, strings.NewReader(encrypted), testDotenvKey); err != nil {
+		t.Fatalf("DecryptDotenv() error = %v", err)
+	}
+	if want := "HOST=db\nLITERAL=\"a $$b\"\nURL=\"${HOST}\\n\"\n"; plain.String() != want {
+		t.Errorf("DecryptDotenv() = %q, want %q", plain.String(), want)
+	}
+
+	rotated := encryptString(t, plain.String(), testRotatedKey)
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte(rotated), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadEncrypted(t, WithEnviron(nil), WithEnvFiles(envFile), WithDotenvKey(testRotatedKey))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := (encryptTestConfig{Host: "db", Literal: "a $b", URL: "db\n"}); cfg != want {
+		t.Errorf("Load() = %+v, want %+v", cfg, want)
+	}
+}
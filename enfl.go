@@ -1,10 +1,12 @@
 package enfl
 
 import (
-	"bufio"
+	"encoding"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -20,6 +22,33 @@ type Loader struct {
 	failOnError bool
 	envFiles    []string
 	autoLoadEnv bool
+	exportEnv   bool                        // copy .env values into the process environment
+	lookupEnv   func(string) (string, bool) // reads a single environment variable
+	environ     func() []string             // lists the environment, nil if it cannot be enumerated
+	sources     []Source                    // precedence chain, highest priority first
+	dotenv      map[string]string           // values read from .env files
+	emptyIsSet  bool                        // treat variables set to "" as set
+	environment string                      // selects .env.{environment} files
+	environVar  string                      // variable holding the environment name
+	searchDirs  []string                    // directories searched for the .env cascade
+	searchUp    bool                        // also search parent directories up to the project root
+	rootMarkers []string                    // files marking the project root
+	loadedFiles []string                    // .env files read by the last Load
+	fileSources []Source                    // structured configuration files, lowest priority first
+	configDirs  []string                    // one-file-per-key directories, highest priority first
+	fileSuffix  bool                        // read KEY_FILE when KEY is not set
+	envKeys     map[string]bool             // environment variable names of the fields being loaded
+	envFS       fs.FS                       // file system holding .env files, nil for the OS
+	configFS    fs.FS                       // file system holding configuration files, nil for the OS
+	remotes     []Source                    // remote documents, lowest priority first
+	secrets     map[string]SecretResolver   // secret resolvers by provider or URL scheme
+	execAllowed []string                    // commands exec tags and values may run, nil disables exec
+	execTimeout time.Duration               // limit for a single command
+	execShell   bool                        // run commands through sh -c
+	dotenvKey   []byte                      // AES key for encrypted .env values
+	keyFile     string                      // file holding the base64 encoded .env key
+	keyVar      string                      // variable holding the base64 encoded .env key
+	decoders    map[reflect.Type]decodeFunc // decoders by field type, ahead of the global registry
 }
 
 type Option func(*Loader)
@@ -45,7 +74,7 @@ func WithFailOnError(failOnError bool) Option {
 	}
 }
 
-// WithEnvFiles specifies .env files to load
+// WithEnvFiles specifies .env files to load after the cascade, later files override earlier ones
 func WithEnvFiles(files ...string) Option {
 	return func(l *Loader) {
 		l.envFiles = files
@@ -59,18 +88,148 @@ func WithAutoLoadEnv(autoLoadEnv bool) Option {
 	}
 }
 
+// WithEnvironment selects the .env.{environment} files of the auto-loaded cascade,
+// overriding the APP_ENV environment variable
+func WithEnvironment(environment string) Option {
+	return func(l *Loader) {
+		l.environment = environment
+	}
+}
+
+// WithEnvironmentVar sets the variable the environment name is read from, APP_ENV by default
+func WithEnvironmentVar(name string) Option {
+	return func(l *Loader) {
+		l.environVar = name
+	}
+}
+
+// WithSearchDirs searches the given directories for the .env cascade, in priority order
+func WithSearchDirs(dirs ...string) Option {
+	return func(l *Loader) {
+		l.searchDirs = dirs
+	}
+}
+
+// WithSearchParents searches the working directory and its parents up to the project root
+// for the .env cascade; files closer to the working directory take precedence
+func WithSearchParents(searchParents bool) Option {
+	return func(l *Loader) {
+		l.searchUp = searchParents
+	}
+}
+
+// WithRootMarkers sets the files or directories marking the project root, go.mod and .git by default
+func WithRootMarkers(markers ...string) Option {
+	return func(l *Loader) {
+		l.rootMarkers = markers
+	}
+}
+
+// WithExportEnv copies values read from .env files into the process environment
+func WithExportEnv(exportEnv bool) Option {
+	return func(l *Loader) {
+		l.exportEnv = exportEnv
+	}
+}
+
+// WithLookupEnv reads environment variables through lookup instead of os.LookupEnv
+func WithLookupEnv(lookup func(string) (string, bool)) Option {
+	return func(l *Loader) {
+		l.lookupEnv = lookup
+		l.environ = nil
+	}
+}
+
+// WithEnviron reads environment variables from a list of KEY=VALUE pairs in the format of os.Environ
+func WithEnviron(environ []string) Option {
+	return func(l *Loader) {
+		values := make(map[string]string, len(environ))
+		for _, kv := range environ {
+			key, value, _ := strings.Cut(kv, "=")
+			values[key] = value
+		}
+		l.lookupEnv = MapLookupEnv(values)
+		l.environ = func() []string {
+			return append([]string(nil), environ...)
+		}
+	}
+}
+
+// MapLookupEnv returns an environment lookup function backed by values
+func MapLookupEnv(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+// WithEmptyIsSet treats values set to an empty string as set, clearing the field instead of
+// falling through to the next source; the allowempty tag overrides it per field
+func WithEmptyIsSet(emptyIsSet bool) Option {
+	return func(l *Loader) {
+		l.emptyIsSet = emptyIsSet
+	}
+}
+
+// WithEnvFS reads .env files from fsys instead of the operating system, e.g. an embed.FS
+// with baked-in defaults. Search directories are paths inside fsys and parent directories
+// are not searched
+func WithEnvFS(fsys fs.FS) Option {
+	return func(l *Loader) {
+		l.envFS = fsys
+	}
+}
+
+// WithConfigFS reads configuration files such as those added with WithJSONFile from fsys
+// instead of the operating system
+func WithConfigFS(fsys fs.FS) Option {
+	return func(l *Loader) {
+		l.configFS = fsys
+	}
+}
+
+// WithFileSuffix controls the Docker secrets convention where KEY_FILE names a file holding
+// the value of KEY; it is on by default and the file tag overrides it per field
+func WithFileSuffix(enabled bool) Option {
+	return func(l *Loader) {
+		l.fileSuffix = enabled
+	}
+}
+
+// WithSources replaces the precedence chain; sources are consulted in order and the first hit wins
+func WithSources(sources ...Source) Option {
+	return func(l *Loader) {
+		l.sources = sources
+	}
+}
+
 // NewLoader creates a new loader with default options
 func NewLoader(opts ...Option) *Loader {
 	l := &Loader{
 		flagSet:     flag.CommandLine,
 		failOnError: true,
 		autoLoadEnv: true,
+		lookupEnv:   os.LookupEnv,
+		environ:     os.Environ,
+		environVar:  "APP_ENV",
+		rootMarkers: []string{"go.mod", ".git"},
+		fileSuffix:  true,
+		keyVar:      "DOTENV_ENCRYPTION_KEY",
 	}
 
 	for _, opt := range opts {
 		opt(l)
 	}
 
+	if l.sources == nil {
+		l.sources = l.defaultSources()
+	}
+	for _, src := range l.sources {
+		if ls, ok := src.(loaderSource); ok {
+			ls.bind(l)
+		}
+	}
+
 	return l
 }
 
@@ -82,24 +241,71 @@ func (l *Loader) Load(config interface{}) error {
 		return fmt.Errorf("config must be a pointer to a struct")
 	}
 
-	// Load .env files (lowest priority after defaults)
-	if err := l.loadEnvFiles(); err != nil {
-		if l.failOnError {
-			return fmt.Errorf("failed to load .env files: %w", err)
-		}
-		fmt.Fprintf(os.Stderr, "config warning: failed to load .env files: %v\n", err)
+	// Nested structs are walked by type, a struct that contains itself would never end
+	if err := l.checkCycles(v.Elem().Type(), nil); err != nil {
+		return err
+	}
+
+	// Let sources read their data (.env files and the like) before any lookup
+	if err := l.prepareSources(); err != nil {
+		return err
 	}
 	// Register flags first
 	if err := l.registerFlags(v.Elem(), ""); err != nil {
 		return fmt.Errorf("failed to register flags: %w", err)
 	}
+	l.envKeys = make(map[string]bool)
+	l.collectEnvKeys(v.Elem().Type(), "")
 
 	// Parse command line flags if using CommandLine and not already parsed
 	if l.flagSet == flag.CommandLine && !flag.Parsed() {
 		flag.Parse()
 	}
 
-	return l.processStruct(v.Elem(), "")
+	_, err := l.processStruct(v.Elem(), "", nil, nil)
+	return err
+}
+
+// prepareSources calls Prepare on every source that implements Preparer
+func (l *Loader) prepareSources() error {
+	for _, src := range l.sources {
+		p, ok := src.(Preparer)
+		if !ok {
+			continue
+		}
+		if err := p.Prepare(); err != nil {
+			if l.failOnError {
+				return fmt.Errorf("failed to load %s source: %w", src.Name(), err)
+			}
+			fmt.Fprintf(os.Stderr, "config warning: failed to load %s source: %v\n", src.Name(), err)
+		}
+	}
+	return nil
+}
+
+// checkCycles returns an error when struct type t, nested through the types on path,
+// contains one of those types again
+func (l *Loader) checkCycles(t reflect.Type, path []reflect.Type) error {
+	for _, outer := range path {
+		if outer == t {
+			return fmt.Errorf("config type %s contains itself", t)
+		}
+	}
+	path = append(path, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || !l.isNestedStruct(field.Type) {
+			continue
+		}
+		nested := field.Type
+		if nested.Kind() == reflect.Ptr {
+			nested = nested.Elem()
+		}
+		if err := l.checkCycles(nested, path); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // registerFlags registers all flags with the flag set
@@ -115,10 +321,14 @@ func (l *Loader) registerFlags(v reflect.Value, prefix string) error {
 			continue
 		}
 
-		// Handle nested structs
-		if field.Kind() == reflect.Struct && fieldType.Type != reflect.TypeOf(time.Time{}) {
+		// Handle nested structs, flags only depend on the type so nil pointers use a scratch value
+		if l.isNestedStruct(fieldType.Type) {
+			nested := field
+			if field.Kind() == reflect.Ptr {
+				nested = reflect.New(field.Type().Elem()).Elem()
+			}
 			nestedPrefix := l.getNestedPrefix(fieldType, prefix)
-			if err := l.registerFlags(field, nestedPrefix); err != nil {
+			if err := l.registerFlags(nested, nestedPrefix); err != nil {
 				return err
 			}
 			continue
@@ -148,6 +358,27 @@ func (l *Loader) registerFieldFlag(field reflect.Value, fieldType reflect.Struct
 	defaultValue := fieldType.Tag.Get("default")
 	usage := l.getFlagUsage(fieldType)
 
+	// Registered decoders validate flag values, the field is decoded from the text later
+	decode, ok := l.decoderFor(field.Type())
+	if !ok && field.Kind() == reflect.Ptr {
+		decode, ok = l.decoderFor(field.Type().Elem())
+	}
+	if ok {
+		l.flagSet.Var(newDecoderFlag(decode), flagName, usage)
+		return nil
+	}
+
+	// Pointer fields take the flag of the type they point to
+	if field.Kind() == reflect.Ptr {
+		field = reflect.New(field.Type().Elem()).Elem()
+	}
+
+	// Types decoding themselves get a fresh instance to validate flag values against
+	if isTextType(field.Type()) && field.Type() != reflect.TypeOf(time.Time{}) {
+		l.flagSet.Var(newTextFlag(field.Type()), flagName, usage)
+		return nil
+	}
+
 	// Register flag based on field type
 	switch field.Kind() {
 	case reflect.String:
@@ -213,6 +444,18 @@ func (l *Loader) registerFieldFlag(field reflect.Value, fieldType reflect.Struct
 	case reflect.Slice:
 		// For slices, use string flag and parse later
 		l.flagSet.String(flagName, defaultValue, usage)
+	case reflect.Map:
+		// Repeatable -name key=value flags, rendered in the field's own syntax for setMapValue
+		l.flagSet.Var(&mapFlag{
+			sep:   tagValue(fieldType.Tag, "sep", ","),
+			kvsep: tagValue(fieldType.Tag, "kvsep", ":"),
+		}, flagName, usage)
+	case reflect.Struct:
+		if field.Type() != reflect.TypeOf(time.Time{}) {
+			return fmt.Errorf("unsupported flag type %s for field %s", field.Kind(), fieldType.Name)
+		}
+		// Timestamps are parsed later like any other string value
+		l.flagSet.String(flagName, defaultValue, usage)
 	default:
 		return fmt.Errorf("unsupported flag type %s for field %s", field.Kind(), fieldType.Name)
 	}
@@ -220,6 +463,72 @@ func (l *Loader) registerFieldFlag(field reflect.Value, fieldType reflect.Struct
 	return nil
 }
 
+// textFlag is the flag of a type decoded from text. Set validates the value and String
+// returns it as given, so the field is decoded from exactly what the user typed. A
+// flag.Value type may collect repeated flags, those are all set on target instead
+type textFlag struct {
+	check  func(string) error
+	value  string
+	target reflect.Value // pointer to the instance collecting a flag.Value, invalid otherwise
+}
+
+// newTextFlag validates values on a fresh instance of a type implementing
+// encoding.TextUnmarshaler or flag.Value
+func newTextFlag(t reflect.Type) *textFlag {
+	pt := reflect.PointerTo(t)
+	if !t.Implements(textUnmarshalerType) && !pt.Implements(textUnmarshalerType) {
+		target := reflect.New(t)
+		return &textFlag{target: target, check: func(value string) error {
+			_, err := setTextValue(target.Elem(), value)
+			return err
+		}}
+	}
+	return &textFlag{check: func(value string) error {
+		_, err := setTextValue(reflect.New(t).Elem(), value)
+		return err
+	}}
+}
+
+// newDecoderFlag validates values with a registered decoder
+func newDecoderFlag(decode decodeFunc) *textFlag {
+	return &textFlag{check: func(value string) error {
+		_, err := decode(value)
+		return err
+	}}
+}
+
+func (f *textFlag) String() string {
+	return f.value
+}
+
+func (f *textFlag) Set(value string) error {
+	if err := f.check(value); err != nil {
+		return err
+	}
+	f.value = value
+	return nil
+}
+
+// mapFlag collects repeated -name key=value flags
+type mapFlag struct {
+	sep   string
+	kvsep string
+	pairs []string
+}
+
+func (f *mapFlag) String() string {
+	return strings.Join(f.pairs, f.sep)
+}
+
+func (f *mapFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	f.pairs = append(f.pairs, escapeSeparators(k, f.sep, f.kvsep)+f.kvsep+escapeSeparators(v, f.sep))
+	return nil
+}
+
 // getFlagUsage generates usage text for a flag
 func (l *Loader) getFlagUsage(field reflect.StructField) string {
 	if usage := field.Tag.Get("usage"); usage != "" {
@@ -249,9 +558,12 @@ func (l *Loader) getFlagUsage(field reflect.StructField) string {
 	return usage
 }
 
-// processStruct processes a struct and its fields
-func (l *Loader) processStruct(v reflect.Value, prefix string) error {
+// processStruct processes a struct and its fields, path holds the enclosing struct fields.
+// Inside an optional section unset required fields are collected in missing rather than
+// failing. It reports whether any field was set
+func (l *Loader) processStruct(v reflect.Value, prefix string, path []reflect.StructField, missing *[]string) (bool, error) {
 	t := v.Type()
+	set := false
 
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
@@ -263,73 +575,234 @@ func (l *Loader) processStruct(v reflect.Value, prefix string) error {
 		}
 
 		// Handle nested structs
-		if field.Kind() == reflect.Struct && fieldType.Type != reflect.TypeOf(time.Time{}) {
+		if l.isNestedStruct(fieldType.Type) {
 			nestedPrefix := l.getNestedPrefix(fieldType, prefix)
-			if err := l.processStruct(field, nestedPrefix); err != nil {
-				return err
+			nestedSet, err := l.processNested(field, nestedPrefix, appendPath(path, fieldType), missing)
+			if err != nil {
+				return set, err
 			}
+			set = set || nestedSet
 			continue
 		}
 
-		if err := l.processField(field, fieldType, prefix); err != nil {
+		fieldSet, err := l.processField(field, fieldType, prefix, path, missing)
+		if err != nil {
 			if l.failOnError {
-				return err
+				return set, err
 			}
 			// Log error but continue
 			fmt.Fprintf(os.Stderr, "config warning: %v\n", err)
 		}
+		set = set || fieldSet
 	}
-	return nil
+	return set, nil
 }
 
-// processField processes a single field
-func (l *Loader) processField(field reflect.Value, fieldType reflect.StructField, prefix string) error {
+// processNested fills a nested struct; a nil pointer to a struct is only allocated when
+// one of its fields is set, so unset sections stay nil and their required fields are not
+// enforced
+func (l *Loader) processNested(field reflect.Value, prefix string, path []reflect.StructField, missing *[]string) (bool, error) {
+	if field.Kind() != reflect.Ptr {
+		return l.processStruct(field, prefix, path, missing)
+	}
+	if !field.IsNil() {
+		return l.processStruct(field.Elem(), prefix, path, missing)
+	}
+
+	var unset []string
+	nested := reflect.New(field.Type().Elem())
+	set, err := l.processStruct(nested.Elem(), prefix, path, &unset)
+	if err != nil || !set {
+		return set, err
+	}
+
+	for _, name := range unset {
+		err := fmt.Errorf("required field %s not set", name)
+		if l.failOnError {
+			return true, err
+		}
+		fmt.Fprintf(os.Stderr, "config warning: %v\n", err)
+	}
+	field.Set(nested)
+	return true, nil
+}
+
+// isNestedStruct reports whether fields of type t are processed as nested structs rather than
+// values; structs with a decoder or decoding themselves from text, like time.Time or
+// netip.Addr, are values
+func (l *Loader) isNestedStruct(t reflect.Type) bool {
+	if l.isValueType(t) {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{}) && !l.isValueType(t)
+}
+
+// processField processes a single field and reports whether a value was found for it
+func (l *Loader) processField(field reflect.Value, fieldType reflect.StructField, prefix string, path []reflect.StructField, missing *[]string) (bool, error) {
 	// Get configuration from struct tags
-	envKey := l.getEnvKey(fieldType, prefix)
-	flagName := l.getFlagName(fieldType)
-	defaultValue := fieldType.Tag.Get("default")
+	key := Key{
+		Field:  fieldType.Name,
+		Env:    l.getEnvKey(fieldType, prefix),
+		Flag:   l.getFlagName(fieldType),
+		Tag:    fieldType.Tag,
+		fields: appendPath(path, fieldType),
+	}
+	for _, f := range key.fields {
+		key.Path = append(key.Path, f.Name)
+	}
 	required := fieldType.Tag.Get("required") == "true"
 
-	// Priority: 1. Flag, 2. Environment, 3. Default
+	allowEmpty := l.allowEmpty(fieldType)
+
+	// Walk the precedence chain, the first source holding the key wins
 	var value string
-	var found bool
+	var from Source
+	for _, src := range l.sources {
+		v, ok, err := src.Lookup(key)
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s from %s source: %w", fieldType.Name, src.Name(), err)
+		}
+		if ok && (v != "" || allowEmpty) {
+			value = v
+			from = src
+			break
+		}
+	}
+	found := from != nil
 
-	// Check command line flag first
-	if flagName != "" {
-		if flagValue := l.getFlagValue(flagName); flagValue != "" {
-			value = flagValue
-			found = true
+	// Check if required, inside an optional section only once the section turns out to be used
+	if required && !found {
+		if missing != nil {
+			*missing = append(*missing, fieldType.Name)
+			return false, nil
 		}
+		return false, fmt.Errorf("required field %s not set", fieldType.Name)
 	}
 
-	// Check environment variable
-	if !found && envKey != "" {
-		if envValue := os.Getenv(envKey); envValue != "" {
-			value = envValue
-			found = true
+	if !found {
+		return false, nil
+	}
+
+	// Repeated flags of a flag.Value type were all set on the instance held by the flag
+	if _, fromFlag := from.(*flagSource); fromFlag && l.setFlagValue(field, key.Flag) {
+		return true, nil
+	}
+
+	// Run exec: commands and replace secret references before converting the value. Commands
+	// only run for environment variables; secrets are resolved in the environment, .env files
+	// and defaults, which may already name local files through KEY_FILE. Configuration files
+	// and config servers may do neither
+	_, fromEnv := from.(*envSource)
+	_, fromDotenv := from.(*dotenvSource)
+	_, fromDefault := from.(*defaultSource)
+	var err error
+	if fromEnv {
+		if value, err = l.resolveExec(value); err != nil {
+			return false, fmt.Errorf("failed to run command for %s: %w", fieldType.Name, err)
+		}
+	}
+	if fromEnv || fromDotenv || fromDefault {
+		if value, err = l.resolveSecret(value); err != nil {
+			return false, fmt.Errorf("failed to resolve secret for %s: %w", fieldType.Name, err)
 		}
 	}
 
-	// Use default value
-	if !found && defaultValue != "" {
-		value = defaultValue
-		found = true
+	// An explicit empty value clears the field, a pointer then points at the zero value
+	if value == "" {
+		if field.Kind() == reflect.Ptr {
+			field.Set(reflect.New(field.Type().Elem()))
+		} else {
+			field.Set(reflect.Zero(field.Type()))
+		}
+		return true, nil
 	}
 
-	// Check if required
-	if required && !found {
-		return fmt.Errorf("required field %s not set", fieldType.Name)
+	if err := l.setField(field, value, fieldType); err != nil {
+		return false, err
 	}
+	return true, nil
+}
+
+// allowEmpty reports whether an empty value counts as set for the field
+func (l *Loader) allowEmpty(field reflect.StructField) bool {
+	if tag, ok := field.Tag.Lookup("allowempty"); ok {
+		allow, err := strconv.ParseBool(tag)
+		return err == nil && allow
+	}
+	return l.emptyIsSet
+}
+
+// fileSuffixEnabled reports whether KEY_FILE is consulted for the field; tag is the field's struct tag
+func (l *Loader) fileSuffixEnabled(tag reflect.StructTag) bool {
+	if value, ok := tag.Lookup("file"); ok {
+		enabled, err := strconv.ParseBool(value)
+		return err == nil && enabled
+	}
+	return l.fileSuffix
+}
 
-	if found {
+// setField sets a struct field from value, honouring its sep, kvsep and layout tags
+func (l *Loader) setField(field reflect.Value, value string, fieldType reflect.StructField) error {
+	// Timestamps in the layout of their tag. Datetimes of structured files arrive as RFC 3339
+	// whatever the layout, so the usual formats are accepted too
+	if layout := fieldType.Tag.Get("layout"); layout != "" && field.Type() == reflect.TypeOf(time.Time{}) {
+		t, err := parseTimeLayout(value, layout)
+		if err != nil {
+			if parsed, timeErr := parseTime(value); timeErr == nil {
+				t, err = parsed, nil
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("invalid time for %s: %v", fieldType.Name, err)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	// Slices and maps with a decoder or their own text decoding are not split
+	if l.isValueType(field.Type()) {
 		return l.setFieldValue(field, value, fieldType.Name)
 	}
 
-	return nil
+	sep := tagValue(fieldType.Tag, "sep", ",")
+	switch field.Kind() {
+	case reflect.Ptr:
+		// Pointers are only allocated once a value is known
+		ptr := reflect.New(field.Type().Elem())
+		if err := l.setField(ptr.Elem(), value, fieldType); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	case reflect.Map:
+		return l.setMapValue(field, value, fieldType.Name, sep, tagValue(fieldType.Tag, "kvsep", ":"))
+	case reflect.Slice:
+		return l.splitSliceValue(field, value, fieldType.Name, sep)
+	}
+	return l.setFieldValue(field, value, fieldType.Name)
+}
+
+// tagValue returns the value of the named tag, or fallback when it is missing or empty
+func tagValue(tag reflect.StructTag, name, fallback string) string {
+	if value := tag.Get(name); value != "" {
+		return value
+	}
+	return fallback
 }
 
 // setFieldValue sets the field value with proper type conversion
 func (l *Loader) setFieldValue(field reflect.Value, value, fieldName string) error {
+	// Registered decoders come first so they can override the built-in conversions
+	if ok, err := l.setDecodedValue(field, value); ok {
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %v", fieldName, err)
+		}
+		return nil
+	}
+
 	// Handle time.Duration as a special case before checking reflect.Kind
 	if field.Type() == reflect.TypeOf(time.Duration(0)) {
 		duration, err := time.ParseDuration(value)
@@ -339,6 +812,20 @@ func (l *Loader) setFieldValue(field reflect.Value, value, fieldName string) err
 		field.Set(reflect.ValueOf(duration))
 		return nil
 	}
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		t, err := parseTime(value)
+		if err != nil {
+			return fmt.Errorf("invalid time for %s: %v", fieldName, err)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if ok, err := setTextValue(field, value); ok {
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %v", fieldName, err)
+		}
+		return nil
+	}
 
 	switch field.Kind() {
 	case reflect.String:
@@ -369,6 +856,14 @@ func (l *Loader) setFieldValue(field reflect.Value, value, fieldName string) err
 		field.SetBool(boolVal)
 	case reflect.Slice:
 		return l.setSliceValue(field, value, fieldName)
+	case reflect.Map:
+		return l.setMapValue(field, value, fieldName, ",", ":")
+	case reflect.Ptr:
+		ptr := reflect.New(field.Type().Elem())
+		if err := l.setFieldValue(ptr.Elem(), value, fieldName); err != nil {
+			return err
+		}
+		field.Set(ptr)
 	default:
 		return fmt.Errorf("unsupported field type %s for %s", field.Kind(), fieldName)
 	}
@@ -376,19 +871,108 @@ func (l *Loader) setFieldValue(field reflect.Value, value, fieldName string) err
 	return nil
 }
 
+// localTimeLayouts are accepted for time.Time values without a zone offset
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseTime parses RFC 3339 timestamps, falling back to local datetimes, dates and unix
+// timestamps in seconds
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err == nil {
+		return t, nil
+	}
+	for _, layout := range localTimeLayouts {
+		if local, localErr := time.ParseInLocation(layout, value, time.Local); localErr == nil {
+			return local, nil
+		}
+	}
+	if sec, unixErr := strconv.ParseInt(value, 10, 64); unixErr == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Time{}, err
+}
+
+// parseTimeLayout parses value with a time.Parse layout, or as a unix timestamp when layout
+// is "unix" (seconds) or "unixmilli"; times without a zone are local
+func parseTimeLayout(value, layout string) (time.Time, error) {
+	switch layout {
+	case "unix", "unixmilli":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix timestamp %q", value)
+		}
+		if layout == "unix" {
+			return time.Unix(n, 0), nil
+		}
+		return time.UnixMilli(n), nil
+	}
+	return time.ParseInLocation(layout, value, time.Local)
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+)
+
+// isTextType reports whether values of type t decode themselves through
+// encoding.TextUnmarshaler or flag.Value, implemented on t or on *t
+func isTextType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		return false // the pointer is allocated and its element decoded
+	}
+	pt := reflect.PointerTo(t)
+	return t.Implements(textUnmarshalerType) || t.Implements(flagValueType) ||
+		pt.Implements(textUnmarshalerType) || pt.Implements(flagValueType)
+}
+
+// setTextValue decodes value with the TextUnmarshaler or flag.Value implemented by field
+// or its address, preferring UnmarshalText. It reports false when field implements neither
+func setTextValue(field reflect.Value, value string) (bool, error) {
+	if !isTextType(field.Type()) {
+		return false, nil
+	}
+
+	target := field
+	if field.CanAddr() {
+		target = field.Addr()
+	}
+	if field.Kind() == reflect.Map && field.IsNil() {
+		field.Set(reflect.MakeMap(field.Type()))
+	}
+
+	if !target.Type().Implements(textUnmarshalerType) && !target.Type().Implements(flagValueType) {
+		target = field // implemented on the value only
+	}
+	if u, ok := target.Interface().(encoding.TextUnmarshaler); ok {
+		return true, u.UnmarshalText([]byte(value))
+	}
+	if v, ok := target.Interface().(flag.Value); ok {
+		return true, v.Set(value)
+	}
+	return false, nil
+}
+
 // setSliceValue handles slice types
 func (l *Loader) setSliceValue(field reflect.Value, value, fieldName string) error {
+	return l.splitSliceValue(field, value, fieldName, ",")
+}
+
+// splitSliceValue fills a slice from value split at every separator not escaped with a backslash
+func (l *Loader) splitSliceValue(field reflect.Value, value, fieldName, separator string) error {
 	if value == "" {
 		return nil
 	}
 
-	separator := ","
-	parts := strings.Split(value, separator)
+	parts := splitUnescaped(value, separator)
 
 	slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
 
 	for i, part := range parts {
-		part = strings.TrimSpace(part)
+		part = unescapeSeparators(strings.TrimSpace(part), separator)
 		elem := slice.Index(i)
 
 		if err := l.setFieldValue(elem, part, fmt.Sprintf("%s[%d]", fieldName, i)); err != nil {
@@ -400,43 +984,170 @@ func (l *Loader) setSliceValue(field reflect.Value, value, fieldName string) err
 	return nil
 }
 
+// setMapValue fills a map from pairs like a:1,b:2, split at separator and kvSeparator
+func (l *Loader) setMapValue(field reflect.Value, value, fieldName, separator, kvSeparator string) error {
+	if value == "" {
+		return nil
+	}
+
+	mapType := field.Type()
+	m := reflect.MakeMap(mapType)
+	for _, pair := range splitUnescaped(value, separator) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := indexUnescaped(pair, kvSeparator)
+		if i < 0 {
+			return fmt.Errorf("invalid map entry %q for %s: expected key%svalue", pair, fieldName, kvSeparator)
+		}
+		k := unescapeSeparators(strings.TrimSpace(pair[:i]), separator, kvSeparator)
+		v := unescapeSeparators(strings.TrimSpace(pair[i+len(kvSeparator):]), separator, kvSeparator)
+
+		key := reflect.New(mapType.Key()).Elem()
+		if err := l.setFieldValue(key, k, fmt.Sprintf("%s key %q", fieldName, k)); err != nil {
+			return err
+		}
+		elem := reflect.New(mapType.Elem()).Elem()
+		if err := l.setFieldValue(elem, v, fmt.Sprintf("%s[%s]", fieldName, k)); err != nil {
+			return err
+		}
+		m.SetMapIndex(key, elem)
+	}
+
+	field.Set(m)
+	return nil
+}
+
+// indexUnescaped returns the index of the first sep in s that is not escaped with a backslash, or -1
+func indexUnescaped(s, sep string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++ // skip the escaped character
+			continue
+		}
+		if strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitUnescaped splits s at every sep that is not escaped with a backslash
+func splitUnescaped(s, sep string) []string {
+	var parts []string
+	for {
+		i := indexUnescaped(s, sep)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+len(sep):]
+	}
+}
+
+// escapeSeparators escapes backslashes and seps in s with a backslash, so map keys and values
+// joined into a single string can be split again
+func escapeSeparators(s string, seps ...string) string {
+	pairs := []string{`\`, `\\`}
+	for _, sep := range seps {
+		pairs = append(pairs, sep, `\`+sep)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// unescapeSeparators reverts escapeSeparators; other backslashes, as in C:\dir, are kept
+func unescapeSeparators(s string, seps ...string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			if escaped := escapedSeparator(s[i+1:], seps); escaped != "" {
+				b.WriteString(escaped)
+				i += len(escaped)
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// escapedSeparator returns the separator or backslash rest starts with, or ""
+func escapedSeparator(rest string, seps []string) string {
+	if strings.HasPrefix(rest, `\`) {
+		return `\`
+	}
+	for _, sep := range seps {
+		if sep != "" && strings.HasPrefix(rest, sep) {
+			return sep
+		}
+	}
+	return ""
+}
+
 // getEnvKey gets the environment variable key for a field
 func (l *Loader) getEnvKey(field reflect.StructField, prefix string) string {
-	if envTag := field.Tag.Get("env"); envTag != "" {
+	names := l.envNames(field, prefix)
+	if len(names) > 1 {
 		// Support multiple env names: env:"PORT,SERVER_PORT"
-		envNames := strings.Split(envTag, ",")
-		for _, name := range envNames {
-			name = strings.TrimSpace(name)
-			if prefix != "" {
-				name = prefix + name
-			}
-			if l.envPrefix != "" {
-				name = l.envPrefix + name
-			}
-			if os.Getenv(name) != "" {
+		for _, name := range names {
+			if l.isEnvSet(Key{Env: name, Tag: field.Tag}, l.allowEmpty(field)) {
 				return name
 			}
 		}
-		// Return first option with prefixes applied
-		name := strings.TrimSpace(envNames[0])
-		if prefix != "" {
-			name = prefix + name
+	}
+	return names[0]
+}
+
+// envNames returns every environment variable name of a field with prefixes applied
+func (l *Loader) envNames(field reflect.StructField, prefix string) []string {
+	envTag := field.Tag.Get("env")
+	if envTag == "" {
+		// Default: convert field name to UPPER_SNAKE_CASE
+		return []string{strings.ToUpper(l.envPrefix + prefix + toSnakeCase(field.Name))}
+	}
+	var names []string
+	for _, name := range strings.Split(envTag, ",") {
+		names = append(names, l.envPrefix+prefix+strings.TrimSpace(name))
+	}
+	return names
+}
+
+// collectEnvKeys records the environment variable names of the fields of struct type t
+func (l *Loader) collectEnvKeys(t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if l.isNestedStruct(field.Type) {
+			nested := field.Type
+			if nested.Kind() == reflect.Ptr {
+				nested = nested.Elem()
+			}
+			l.collectEnvKeys(nested, l.getNestedPrefix(field, prefix))
+			continue
 		}
-		if l.envPrefix != "" {
-			name = l.envPrefix + name
+		for _, name := range l.envNames(field, prefix) {
+			l.envKeys[name] = true
 		}
-		return name
 	}
+}
 
-	// Default: convert field name to UPPER_SNAKE_CASE
-	envKey := toSnakeCase(field.Name)
-	if prefix != "" {
-		envKey = prefix + envKey
-	}
-	if l.envPrefix != "" {
-		envKey = l.envPrefix + envKey
+// isEnvSet reports whether a source keyed by environment variable name holds a value for key.Env
+func (l *Loader) isEnvSet(key Key, allowEmpty bool) bool {
+	for _, src := range l.sources {
+		if _, ok := src.(envKeyedSource); !ok {
+			continue
+		}
+		if value, ok, err := src.Lookup(key); ok && err == nil && (value != "" || allowEmpty) {
+			return true
+		}
 	}
-	return strings.ToUpper(envKey)
+	return false
 }
 
 // getFlagName gets the flag name for a field
@@ -451,8 +1162,35 @@ func (l *Loader) getFlagName(field reflect.StructField) string {
 	return toKebabCase(field.Name)
 }
 
-// getFlagValue gets value from command line flags
-func (l *Loader) getFlagValue(name string) string {
+// setFlagValue copies the instance collecting the flag name into field and reports whether
+// the flag holds one
+func (l *Loader) setFlagValue(field reflect.Value, name string) bool {
+	f := l.flagSet.Lookup(name)
+	if f == nil {
+		return false
+	}
+	tf, ok := f.Value.(*textFlag)
+	if !ok || !tf.target.IsValid() {
+		return false
+	}
+	if field.Kind() == reflect.Ptr {
+		if field.Type().Elem() != tf.target.Type().Elem() {
+			return false
+		}
+		ptr := reflect.New(field.Type().Elem())
+		ptr.Elem().Set(tf.target.Elem())
+		field.Set(ptr)
+		return true
+	}
+	if field.Type() != tf.target.Type().Elem() {
+		return false
+	}
+	field.Set(tf.target.Elem())
+	return true
+}
+
+// getFlagValue gets value from command line flags and whether the user set it
+func (l *Loader) getFlagValue(name string) (string, bool) {
 	if f := l.flagSet.Lookup(name); f != nil {
 		// Check if the flag was actually set by the user
 		visited := false
@@ -464,10 +1202,15 @@ func (l *Loader) getFlagValue(name string) string {
 
 		// Only return flag value if it was explicitly set by user
 		if visited {
-			return f.Value.String()
+			return f.Value.String(), true
 		}
 	}
-	return ""
+	return "", false
+}
+
+// appendPath returns path extended by field without sharing the backing array
+func appendPath(path []reflect.StructField, field reflect.StructField) []reflect.StructField {
+	return append(path[:len(path):len(path)], field)
 }
 
 // getNestedPrefix gets the prefix for nested structs
@@ -496,96 +1239,175 @@ func toKebabCase(s string) string {
 
 // loadEnvFiles loads environment variables from .env files
 func (l *Loader) loadEnvFiles() error {
-	filesToLoad := l.envFiles
+	l.dotenv = make(map[string]string)
+	l.loadedFiles = nil
+	var filesToLoad []string
 	if l.autoLoadEnv {
-		commonEnvFiles := []string{
-			".env",
-			".env.local",
-			".env.development",
-			".env.production",
-		}
-		// Only include files that exist
-		for _, file := range commonEnvFiles {
-			if _, err := os.Stat(file); err == nil {
-				filesToLoad = append(filesToLoad, file)
+		dirs, err := l.envSearchDirs()
+		if err != nil {
+			return err
+		}
+		// Lowest priority directory first so closer files override it
+		cascade := l.envFileCascade()
+		for i := len(dirs) - 1; i >= 0; i-- {
+			// Only include files that exist
+			for _, name := range cascade {
+				file := joinPath(l.envFS, dirs[i], name)
+				// An encrypted sibling such as .env.production.enc overrides its plain file
+				for _, candidate := range []string{file, file + ".enc"} {
+					if fileExists(l.envFS, candidate) {
+						filesToLoad = append(filesToLoad, candidate)
+					}
+				}
 			}
 		}
 	}
+	// Explicit files come last so they override the cascade
+	filesToLoad = append(filesToLoad, l.envFiles...)
 
-	// Load each file
+	// Load each file, later files take precedence over earlier ones. ${VAR} references
+	// expand against the file itself and the values of the files loaded before it
+	values := make(map[string]string)
+	var order []string
 	for _, file := range filesToLoad {
-		if err := l.loadEnvFile(file); err != nil {
+		fileEntries, err := l.loadEnvFile(file)
+		if err != nil {
 			return fmt.Errorf("failed to load %s: %w", file, err)
 		}
-	}
 
-	return nil
-}
+		entries := make(map[string]dotenvEntry, len(fileEntries))
+		var fileOrder []string
+		for _, entry := range fileEntries {
+			if _, exists := entries[entry.key]; !exists {
+				fileOrder = append(fileOrder, entry.key)
+			}
+			entries[entry.key] = entry
+		}
+		expanded, err := newDotenvResolver(entries, values, l.lookupEnv).resolveAll(fileOrder)
+		if err != nil {
+			return err
+		}
 
-// loadEnvFile loads a single .env file
-func (l *Loader) loadEnvFile(filename string) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return fmt.Errorf("failed to open %s: %w", filename, err)
+		for _, key := range fileOrder {
+			if _, exists := values[key]; !exists {
+				order = append(order, key)
+			}
+			values[key] = expanded[key]
+		}
+		l.loadedFiles = append(l.loadedFiles, file)
 	}
-	defer file.Close()
+	l.dotenv = values
 
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
-
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
+	// Export only if opted in and not already set (environment variables take precedence)
+	if !l.exportEnv {
+		return nil
+	}
+	for _, key := range order {
+		if _, exists := l.lookupEnv(key); exists {
 			continue
 		}
-
-		// Parse KEY=VALUE format
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid format at line %d: %s", lineNum, line)
+		if err := os.Setenv(key, values[key]); err != nil {
+			return fmt.Errorf("failed to export %s: %w", key, err)
 		}
+	}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+	return nil
+}
 
-		// Skip if key is empty
-		if key == "" || value == "" {
-			continue
+// envSearchDirs lists the directories searched for .env files, highest priority first
+func (l *Loader) envSearchDirs() ([]string, error) {
+	dirs := append([]string(nil), l.searchDirs...)
+	if l.searchUp && l.envFS == nil {
+		parents, err := l.parentDirs()
+		if err != nil {
+			return nil, err
 		}
+		dirs = append(dirs, parents...)
+	}
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	return dirs, nil
+}
+
+// parentDirs walks up from the working directory to the first directory holding a root marker.
+// Only the working directory is returned when no marker is found, so files outside the project are never read.
+func (l *Loader) parentDirs() ([]string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
 
-		// Handle Quated Values
-		value = l.unquoteValue(value)
-		// Only set if not already set (environment variables take precedence)
-		if _, exists := os.LookupEnv(key); !exists {
-			os.Setenv(key, value)
+	var dirs []string
+	for dir := wd; ; {
+		dirs = append(dirs, dir)
+		for _, marker := range l.rootMarkers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dirs, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return []string{wd}, nil
 		}
+		dir = parent
 	}
+}
 
-	return scanner.Err()
+// LoadedFiles returns the .env files read by the last Load, from lowest to highest priority
+func (l *Loader) LoadedFiles() []string {
+	return append([]string(nil), l.loadedFiles...)
 }
 
-// unquoteValue removes quotes from values and handles escape sequences
-func (l *Loader) unquoteValue(value string) string {
-	// Handle double quotes
-	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
-		value = value[1 : len(value)-1]
-		// Handle escape sequences
-		value = strings.ReplaceAll(value, `\"`, `"`)
-		value = strings.ReplaceAll(value, `\\`, `\`)
-		value = strings.ReplaceAll(value, `\n`, "\n")
-		value = strings.ReplaceAll(value, `\r`, "\r")
-		value = strings.ReplaceAll(value, `\t`, "\t")
-		return value
+// envFileCascade lists the auto-loaded .env files from lowest to highest priority:
+// .env, .env.{environment}, .env.local, .env.{environment}.local
+// The .local files hold machine specific overrides and are skipped in the test environment
+// so tests behave the same everywhere.
+func (l *Loader) envFileCascade() []string {
+	environment := l.environment
+	if environment == "" && l.environVar != "" {
+		environment, _ = l.lookupEnv(l.environVar)
 	}
 
-	// Handle single quotes (no escape sequences)
-	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
-		return value[1 : len(value)-1]
+	files := []string{".env"}
+	if environment != "" {
+		files = append(files, ".env."+environment)
+	}
+	if environment == "test" {
+		return files
 	}
+	files = append(files, ".env.local")
+	if environment != "" {
+		files = append(files, ".env."+environment+".local")
+	}
+	return files
+}
 
-	return value
+// loadEnvFile reads the entries of a single .env file, decrypting enc:v1: values
+func (l *Loader) loadEnvFile(filename string) ([]dotenvEntry, error) {
+	file, err := openFile(l.envFS, filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	entries, err := parseDotenv(file, filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.decryptEntries(entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// getenv looks a variable up in the environment first and the .env files second
+func (l *Loader) getenv(name string) (string, bool) {
+	if value, ok := l.lookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := l.dotenv[name]
+	return value, ok
 }
 
 // Convenience functions
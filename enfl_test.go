@@ -1,7 +1,14 @@
 package enfl
 
 import (
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -254,6 +261,23 @@ func TestSetFieldValue(t *testing.T) {
 			wantErr:   false,
 			expected:  []bool{true, false, true},
 		},
+
+		// Pointer tests
+		{
+			name:      "Int Pointer - Valid",
+			field:     (*int)(nil),
+			value:     "42",
+			fieldName: "IntPointerField",
+			wantErr:   false,
+			expected:  intPtr(42),
+		},
+		{
+			name:      "Duration Pointer - Invalid",
+			field:     (*time.Duration)(nil),
+			value:     "soon",
+			fieldName: "DurationPointerField",
+			wantErr:   true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -325,6 +349,13 @@ func TestSetSliceValue(t *testing.T) {
 			wantErr:   false,
 			expected:  []float64{1.1, 2.2, 3.3},
 		},
+		{
+			name:      "Escaped Separators",
+			sliceType: []string{},
+			value:     `a\,b,c\\,C:\dir`,
+			fieldName: "StringSlice",
+			expected:  []string{"a,b", `c\`, `C:\dir`},
+		},
 		{
 			name:      "Empty Slice Value",
 			sliceType: []string{},
@@ -359,3 +390,701 @@ func TestSetSliceValue(t *testing.T) {
 		})
 	}
 }
+
+func TestSetMapValue(t *testing.T) {
+	l := NewLoader()
+
+	tests := []struct {
+		name     string
+		mapType  interface{}
+		value    string
+		sep      string
+		kvsep    string
+		wantErr  bool
+		expected interface{}
+	}{
+		{
+			name:     "String Map",
+			mapType:  map[string]string{},
+			value:    "env:prod, team : core",
+			sep:      ",",
+			kvsep:    ":",
+			expected: map[string]string{"env": "prod", "team": "core"},
+		},
+		{
+			name:     "Int Map",
+			mapType:  map[string]int{},
+			value:    "a:1,b:2,",
+			sep:      ",",
+			kvsep:    ":",
+			expected: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name:     "Custom Separators",
+			mapType:  map[string]string{},
+			value:    "url=http://x:80;empty=",
+			sep:      ";",
+			kvsep:    "=",
+			expected: map[string]string{"url": "http://x:80", "empty": ""},
+		},
+		{
+			name:     "Duration Values And Int Keys",
+			mapType:  map[int]time.Duration{},
+			value:    "1:5s,2:1m",
+			sep:      ",",
+			kvsep:    ":",
+			expected: map[int]time.Duration{1: 5 * time.Second, 2: time.Minute},
+		},
+		{
+			name:     "Escaped Separators",
+			mapType:  map[string]string{},
+			value:    `owner:a\,b,k\:ey:x\\y,path:C:\dir`,
+			sep:      ",",
+			kvsep:    ":",
+			expected: map[string]string{"owner": "a,b", "k:ey": `x\y`, "path": `C:\dir`},
+		},
+		{
+			name:    "Missing Separator",
+			mapType: map[string]string{},
+			value:   "a:1,b",
+			sep:     ",",
+			kvsep:   ":",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid Value",
+			mapType: map[string]int{},
+			value:   "a:one",
+			sep:     ",",
+			kvsep:   ":",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := reflect.New(reflect.TypeOf(tt.mapType)).Elem()
+
+			err := l.setMapValue(v, tt.value, "Labels", tt.sep, tt.kvsep)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setMapValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(v.Interface(), tt.expected) {
+				t.Errorf("setMapValue() got = %v, want %v", v.Interface(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestMapFields(t *testing.T) {
+	type config struct {
+		Labels  map[string]string        `env:"LABELS" flag:"label"`
+		Limits  map[string]int           `env:"LIMITS" sep:";" kvsep:"=" default:"cpu=2;mem=512"`
+		Tags    []string                 `env:"TAGS" sep:"|"`
+		Timeout map[string]time.Duration `env:"TIMEOUT"`
+	}
+
+	jsonFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"labels": {"Env": "prod", "Team": "core", "owner": "a,b", "a:b": "c\\d"}, "limits": {"cpu": 4}, "timeout": "read:5s"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	iniFile := filepath.Join(t.TempDir(), "config.ini")
+	if err := os.WriteFile(iniFile, []byte("[limits]\ncpu = 8\nmem = 1024\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		environ  []string
+		args     []string
+		opts     []Option
+		expected config
+	}{
+		{
+			name:    "Environment And Defaults",
+			environ: []string{"LABELS=env:prod,team:core", "TAGS=a,b|c"},
+			expected: config{
+				Labels: map[string]string{"env": "prod", "team": "core"},
+				Limits: map[string]int{"cpu": 2, "mem": 512},
+				Tags:   []string{"a,b", "c"},
+			},
+		},
+		{
+			name: "Repeated Flags",
+			args: []string{"-label", "env=prod", "-label", "url=http://x:80", "-label", "owner=a,b", "-label", `path=C:\dir`, "-limits", "cpu=1"},
+			expected: config{
+				Labels: map[string]string{"env": "prod", "url": "http://x:80", "owner": "a,b", "path": `C:\dir`},
+				Limits: map[string]int{"cpu": 1},
+			},
+		},
+		{
+			name: "JSON Objects",
+			opts: []Option{WithJSONFile(jsonFile)},
+			expected: config{
+				Labels:  map[string]string{"Env": "prod", "Team": "core", "owner": "a,b", "a:b": `c\d`},
+				Limits:  map[string]int{"cpu": 4},
+				Timeout: map[string]time.Duration{"read": 5 * time.Second},
+			},
+		},
+		{
+			name: "INI Sections",
+			opts: []Option{WithINIFile(iniFile)},
+			expected: config{
+				Limits: map[string]int{"cpu": 8, "mem": 1024},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			opts := append([]Option{
+				WithFlagSet(flagSet),
+				WithAutoLoadEnv(false),
+				WithEnviron(tt.environ),
+			}, tt.opts...)
+			l := NewLoader(opts...)
+
+			var cfg config
+			// Register the flags before parsing, the way Load does
+			if err := l.registerFlags(reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+				t.Fatalf("registerFlags() error = %v", err)
+			}
+			if err := flagSet.Parse(tt.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if err := l.Load(&cfg); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !reflect.DeepEqual(cfg, tt.expected) {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestPointerFields(t *testing.T) {
+	type tls struct {
+		Cert string `env:"CERT"`
+		Key  string `env:"KEY"`
+	}
+	type config struct {
+		Port    *int           `env:"PORT" flag:"port"`
+		Name    *string        `env:"NAME" allowempty:"true"`
+		Timeout *time.Duration `env:"TIMEOUT"`
+		Debug   *bool          `env:"DEBUG" default:"false"`
+		Hosts   *[]string      `env:"HOSTS"`
+		TLS     *tls           `prefix:"TLS_"`
+	}
+
+	tests := []struct {
+		name  string
+		env   []string
+		args  []string
+		check func(t *testing.T, cfg config)
+	}{
+		{
+			name: "Unset Stays Nil",
+			check: func(t *testing.T, cfg config) {
+				if cfg.Port != nil || cfg.Name != nil || cfg.Timeout != nil || cfg.Hosts != nil || cfg.TLS != nil {
+					t.Errorf("Load() = %+v, want nil pointers", cfg)
+				}
+				if cfg.Debug == nil || *cfg.Debug {
+					t.Errorf("Debug = %v, want pointer to default false", cfg.Debug)
+				}
+			},
+		},
+		{
+			name: "Set Values Are Allocated",
+			env:  []string{"PORT=0", "NAME=", "TIMEOUT=5s", "HOSTS=a,b", "TLS_CERT=cert.pem"},
+			check: func(t *testing.T, cfg config) {
+				if cfg.Port == nil || *cfg.Port != 0 {
+					t.Errorf("Port = %v, want pointer to 0", cfg.Port)
+				}
+				if cfg.Name == nil || *cfg.Name != "" {
+					t.Errorf("Name = %v, want pointer to empty string", cfg.Name)
+				}
+				if cfg.Timeout == nil || *cfg.Timeout != 5*time.Second {
+					t.Errorf("Timeout = %v, want pointer to 5s", cfg.Timeout)
+				}
+				if cfg.Hosts == nil || !reflect.DeepEqual(*cfg.Hosts, []string{"a", "b"}) {
+					t.Errorf("Hosts = %v, want pointer to [a b]", cfg.Hosts)
+				}
+				if cfg.TLS == nil || *cfg.TLS != (tls{Cert: "cert.pem"}) {
+					t.Errorf("TLS = %+v, want allocated with Cert set", cfg.TLS)
+				}
+			},
+		},
+		{
+			name: "Flag",
+			args: []string{"-port", "9000"},
+			check: func(t *testing.T, cfg config) {
+				if cfg.Port == nil || *cfg.Port != 9000 {
+					t.Errorf("Port = %v, want pointer to 9000", cfg.Port)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			l := NewLoader(WithFlagSet(flagSet), WithAutoLoadEnv(false), WithEnviron(tt.env))
+
+			var cfg config
+			if err := l.registerFlags(reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+				t.Fatalf("registerFlags() error = %v", err)
+			}
+			if err := flagSet.Parse(tt.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if err := l.Load(&cfg); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestPointerStructKeepsExistingValue(t *testing.T) {
+	type nested struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT"`
+	}
+	cfg := struct {
+		DB *nested `prefix:"DB_"`
+	}{DB: &nested{Host: "preset"}}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron([]string{"DB_PORT=5432"}),
+	)
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if *cfg.DB != (nested{Host: "preset", Port: 5432}) {
+		t.Errorf("DB = %+v, want preset host kept and port set", cfg.DB)
+	}
+}
+
+// testLevel implements encoding.TextUnmarshaler on its pointer
+type testLevel int
+
+func (lv *testLevel) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "debug":
+		*lv = -1
+	case "info":
+		*lv = 0
+	case "warn":
+		*lv = 1
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+// testList implements flag.Value and is not split like other slices
+type testList []string
+
+func (tl *testList) String() string { return strings.Join(*tl, "|") }
+
+func (tl *testList) Set(value string) error {
+	*tl = strings.Split(value, "|")
+	return nil
+}
+
+// testItems implements flag.Value and appends every value, like a repeatable flag
+type testItems []string
+
+func (ti *testItems) String() string { return strings.Join(*ti, ",") }
+
+func (ti *testItems) Set(value string) error {
+	*ti = append(*ti, value)
+	return nil
+}
+
+func TestTextFields(t *testing.T) {
+	type config struct {
+		Level  testLevel   `env:"LEVEL" flag:"level" default:"info"`
+		Levels []testLevel `env:"LEVELS"`
+		Ptr    *testLevel  `env:"PTR_LEVEL"`
+		Addr   netip.Addr  `env:"ADDR" flag:"addr"`
+		List   testList    `env:"LIST" flag:"list"`
+		Items  testItems   `flag:"item"`
+		More   *testItems  `flag:"more"`
+	}
+
+	tests := []struct {
+		name     string
+		env      []string
+		args     []string
+		expected config
+		wantErr  bool
+	}{
+		{
+			name:     "Default",
+			expected: config{Level: 0},
+		},
+		{
+			name:     "Env",
+			env:      []string{"LEVEL=debug", "LEVELS=warn,debug", "ADDR=10.0.0.1", "LIST=a,b|c"},
+			expected: config{Level: -1, Levels: []testLevel{1, -1}, Addr: netip.MustParseAddr("10.0.0.1"), List: testList{"a,b", "c"}},
+		},
+		{
+			name:     "Flags",
+			env:      []string{"LEVEL=debug"},
+			args:     []string{"-level", "warn", "-addr", "::1", "-list", "x|y", "-item", "a", "-item", "b", "-more", "c", "-more", "d"},
+			expected: config{Level: 1, Addr: netip.MustParseAddr("::1"), List: testList{"x", "y"}, Items: testItems{"a", "b"}, More: &testItems{"c", "d"}},
+		},
+		{
+			name:    "Invalid Env",
+			env:     []string{"LEVEL=loud"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			l := NewLoader(WithFlagSet(flagSet), WithAutoLoadEnv(false), WithEnviron(tt.env))
+
+			var cfg config
+			if err := l.registerFlags(reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+				t.Fatalf("registerFlags() error = %v", err)
+			}
+			if err := flagSet.Parse(tt.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			err := l.Load(&cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(cfg, tt.expected) {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+
+	t.Run("Pointer", func(t *testing.T) {
+		l := NewLoader(
+			WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+			WithAutoLoadEnv(false),
+			WithEnviron([]string{"PTR_LEVEL=warn"}),
+		)
+		var cfg config
+		if err := l.Load(&cfg); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if cfg.Ptr == nil || *cfg.Ptr != 1 {
+			t.Errorf("Ptr = %v, want pointer to warn", cfg.Ptr)
+		}
+	})
+
+	t.Run("Invalid Flag", func(t *testing.T) {
+		flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+		flagSet.SetOutput(io.Discard)
+		l := NewLoader(WithFlagSet(flagSet), WithAutoLoadEnv(false))
+		var cfg config
+		if err := l.registerFlags(reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+			t.Fatalf("registerFlags() error = %v", err)
+		}
+		if err := flagSet.Parse([]string{"-addr", "nope"}); err == nil {
+			t.Error("Parse() error = nil, want invalid address")
+		}
+	})
+}
+
+func TestPointerStructRequiredFields(t *testing.T) {
+	type config struct {
+		TLS *struct {
+			Cert string `env:"CERT" required:"true"`
+			Key  string `env:"KEY"`
+		} `prefix:"TLS_"`
+	}
+
+	tests := []struct {
+		name    string
+		env     []string
+		wantTLS bool
+		wantErr string
+	}{
+		{name: "Unused Section", wantTLS: false},
+		{name: "Complete Section", env: []string{"TLS_CERT=cert.pem"}, wantTLS: true},
+		{name: "Incomplete Section", env: []string{"TLS_KEY=key.pem"}, wantErr: "required field Cert not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoader(WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)), WithAutoLoadEnv(false), WithEnviron(tt.env))
+			var cfg config
+			err := l.Load(&cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if (cfg.TLS != nil) != tt.wantTLS {
+				t.Errorf("TLS = %+v, want allocated %v", cfg.TLS, tt.wantTLS)
+			}
+		})
+	}
+}
+
+func TestRecursiveStruct(t *testing.T) {
+	type node struct {
+		Name string `env:"NAME"`
+		Next *node  `prefix:"NEXT_"`
+	}
+	type config struct {
+		Head node `prefix:"HEAD_"`
+	}
+
+	l := NewLoader(WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)), WithAutoLoadEnv(false), WithEnviron([]string{"HEAD_NAME=a"}))
+	var cfg config
+	err := l.Load(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "contains itself") {
+		t.Fatalf("Load() error = %v, want a recursive type error", err)
+	}
+}
+
+// TestEnvFilesStayPrivate checks that .env values reach the config without touching the process environment
+func TestEnvFilesStayPrivate(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("ENFL_PRIVATE_NAME=from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type config struct {
+		Name string `env:"ENFL_PRIVATE_NAME"`
+	}
+
+	tests := []struct {
+		name      string
+		exportEnv bool
+	}{
+		{name: "Private", exportEnv: false},
+		{name: "Exported", exportEnv: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Register cleanup for the variable, then make sure it starts unset
+			t.Setenv("ENFL_PRIVATE_NAME", "")
+			os.Unsetenv("ENFL_PRIVATE_NAME")
+
+			l := NewLoader(
+				WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)),
+				WithAutoLoadEnv(false),
+				WithEnvFiles(envFile),
+				WithExportEnv(tt.exportEnv),
+			)
+
+			var cfg config
+			if err := l.Load(&cfg); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Name != "from-file" {
+				t.Errorf("Name = %q, want %q", cfg.Name, "from-file")
+			}
+
+			_, exported := os.LookupEnv("ENFL_PRIVATE_NAME")
+			if exported != tt.exportEnv {
+				t.Errorf("process env set = %v, want %v", exported, tt.exportEnv)
+			}
+		})
+	}
+}
+
+func TestEnvFileCascade(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected []string
+	}{
+		{
+			name:     "No Environment",
+			opts:     []Option{WithEnviron(nil)},
+			expected: []string{".env", ".env.local"},
+		},
+		{
+			name:     "Option",
+			opts:     []Option{WithEnviron([]string{"APP_ENV=staging"}), WithEnvironment("production")},
+			expected: []string{".env", ".env.production", ".env.local", ".env.production.local"},
+		},
+		{
+			name:     "APP_ENV",
+			opts:     []Option{WithEnviron([]string{"APP_ENV=development"})},
+			expected: []string{".env", ".env.development", ".env.local", ".env.development.local"},
+		},
+		{
+			name:     "Custom Variable",
+			opts:     []Option{WithEnviron([]string{"GO_ENV=staging"}), WithEnvironmentVar("GO_ENV")},
+			expected: []string{".env", ".env.staging", ".env.local", ".env.staging.local"},
+		},
+		{
+			name:     "Test Skips Local",
+			opts:     []Option{WithEnvironment("test")},
+			expected: []string{".env", ".env.test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewLoader(tt.opts...).envFileCascade()
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("envFileCascade() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnvFileCascadePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".env":                  "A=env\nB=env\nC=env\nD=env\n",
+		".env.production":       "B=production\nC=production\nD=production\n",
+		".env.local":            "C=local\nD=local\n",
+		".env.production.local": "D=production.local\n",
+		".env.development":      "A=development\nB=development\nC=development\nD=development\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+
+	type config struct {
+		A, B, C, D string
+	}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithEnviron(nil),
+		WithEnvironment("production"),
+	)
+
+	var cfg config
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	expected := config{A: "env", B: "production", C: "local", D: "production.local"}
+	if cfg != expected {
+		t.Errorf("Load() = %+v, want %+v", cfg, expected)
+	}
+}
+
+// TestEnvFilesOrder pins the documented order of explicit files: later files win
+func TestEnvFilesOrder(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.env"), filepath.Join(dir, "b.env")
+	if err := os.WriteFile(first, []byte("A=first\nB=first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("A=second\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg struct{ A, B string }
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron(nil),
+		WithEnvFiles(first, second),
+	)
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.A != "second" || cfg.B != "first" {
+		t.Errorf("Load() = %+v, want A from b.env and B from a.env", cfg)
+	}
+}
+
+func TestEnvSearchDirs(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := filepath.Join(root, "internal", "pkg")
+	shared := filepath.Join(root, "shared")
+	files := map[string]string{
+		filepath.Join(root, "go.mod"):     "module example\n",
+		filepath.Join(root, ".env"):       "A=root\nB=root\nC=root\n",
+		filepath.Join(pkg, ".env"):        "B=pkg\n",
+		filepath.Join(shared, ".env"):     "C=shared\n",
+		filepath.Join(root, "..", ".env"): "A=outside\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(pkg)
+
+	type config struct {
+		A, B, C string
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected config
+		loaded   []string
+	}{
+		{
+			name:     "Working Directory Only",
+			expected: config{B: "pkg"},
+			loaded:   []string{".env"},
+		},
+		{
+			name:     "Search Parents",
+			opts:     []Option{WithSearchParents(true)},
+			expected: config{A: "root", B: "pkg", C: "root"},
+			loaded:   []string{filepath.Join(root, ".env"), filepath.Join(pkg, ".env")},
+		},
+		{
+			name:     "Search Dirs",
+			opts:     []Option{WithSearchDirs(shared, root)},
+			expected: config{A: "root", B: "root", C: "shared"},
+			loaded:   []string{filepath.Join(root, ".env"), filepath.Join(shared, ".env")},
+		},
+		{
+			name:     "No Root Marker",
+			opts:     []Option{WithSearchParents(true), WithRootMarkers("does-not-exist")},
+			expected: config{B: "pkg"},
+			loaded:   []string{filepath.Join(pkg, ".env")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{
+				WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)),
+				WithEnviron(nil),
+			}, tt.opts...)
+			l := NewLoader(opts...)
+
+			var cfg config
+			if err := l.Load(&cfg); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg != tt.expected {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+			if got := l.LoadedFiles(); !reflect.DeepEqual(got, tt.loaded) {
+				t.Errorf("LoadedFiles() = %v, want %v", got, tt.loaded)
+			}
+		})
+	}
+}
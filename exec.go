@@ -0,0 +1,185 @@
+package enfl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// WithExec enables running credential helpers and lists the commands that may run, e.g.
+// WithExec("pass", "aws"). Commands come from exec tags or environment variables prefixed
+// with "exec:" and their first word must match an entry exactly. Without this option exec
+// tags are an error and exec: values are used literally
+func WithExec(allowed ...string) Option {
+	return func(l *Loader) {
+		l.execAllowed = append(l.execAllowed, allowed...)
+	}
+}
+
+// WithExecTimeout limits how long a command may run, 10 seconds by default
+func WithExecTimeout(timeout time.Duration) Option {
+	return func(l *Loader) {
+		l.execTimeout = timeout
+	}
+}
+
+// WithExecShell runs commands through sh -c so pipes and variables work. The shell interprets
+// the whole command, so each command must then match an entry of WithExec exactly, e.g.
+// WithExec("aws configure get region | tr -d ' '"). Commands are split into arguments and run
+// directly by default
+func WithExecShell(shell bool) Option {
+	return func(l *Loader) {
+		l.execShell = shell
+	}
+}
+
+// ExecSource returns a Source running the command in a field's exec tag and using its
+// standard output, without the trailing newline, as the value. It sits just above defaults
+// in the default chain
+func ExecSource() Source {
+	return &execSource{}
+}
+
+type execSource struct {
+	l *Loader
+}
+
+func (s *execSource) bind(l *Loader) { s.l = l }
+
+func (s *execSource) Name() string { return "exec" }
+
+func (s *execSource) Lookup(key Key) (string, bool, error) {
+	command := key.Tag.Get("exec")
+	if command == "" {
+		return "", false, nil
+	}
+	if len(s.l.execAllowed) == 0 {
+		return "", false, fmt.Errorf("exec tag requires WithExec")
+	}
+	value, err := s.l.runCommand(command)
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// resolveExec runs the command of an exec: value when exec is enabled; callers only pass
+// values read from the environment
+func (l *Loader) resolveExec(value string) (string, error) {
+	command, ok := strings.CutPrefix(value, "exec:")
+	if !ok || len(l.execAllowed) == 0 {
+		return value, nil
+	}
+	return l.runCommand(strings.TrimSpace(command))
+}
+
+// runCommand runs an allowed command and returns its output without the trailing newline
+func (l *Loader) runCommand(command string) (string, error) {
+	var args []string
+	if l.execShell {
+		// Anything after the first word may be more shell commands, so allow only whole commands
+		if !l.execAllowedCommand(command) {
+			return "", fmt.Errorf("command %q is not allowed", command)
+		}
+		args = []string{"sh", "-c", command}
+	} else {
+		var err error
+		if args, err = splitCommand(command); err != nil {
+			return "", err
+		}
+		if len(args) == 0 {
+			return "", fmt.Errorf("empty command")
+		}
+		if !l.execAllowedCommand(args[0]) {
+			return "", fmt.Errorf("command %s is not allowed", args[0])
+		}
+	}
+
+	timeout := l.execTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("command %s timed out after %s", args[0], timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("command %s failed: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("command %s failed: %w", args[0], err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+func (l *Loader) execAllowedCommand(name string) bool {
+	for _, allowed := range l.execAllowed {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCommand splits command into arguments, honouring single quotes, double quotes and backslash escapes
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote byte
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				arg.WriteByte(c)
+			}
+		case c == '\\' && quote != '\'':
+			if i+1 == len(command) {
+				return nil, fmt.Errorf("trailing backslash in command")
+			}
+			i++
+			arg.WriteByte(command[i])
+			inArg = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				arg.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in command", quote)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
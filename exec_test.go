@@ -0,0 +1,161 @@
+package enfl
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecSource(t *testing.T) {
+	type config struct {
+		Password string `env:"DB_PASSWORD" exec:"echo 'from tag'" default:"fallback"`
+		Token    string `env:"TOKEN"`
+		Count    int    `env:"COUNT"`
+	}
+
+	tests := []struct {
+		name     string
+		environ  []string
+		opts     []Option
+		expected config
+		wantErr  string
+	}{
+		{
+			name:     "Tag",
+			opts:     []Option{WithExec("echo")},
+			expected: config{Password: "from tag"},
+		},
+		{
+			name:     "Env Overrides Tag",
+			environ:  []string{"DB_PASSWORD=plain"},
+			opts:     []Option{WithExec("echo")},
+			expected: config{Password: "plain"},
+		},
+		{
+			name:     "Value Prefix",
+			environ:  []string{"TOKEN=exec:printf %s tok", "COUNT=exec: echo 42"},
+			opts:     []Option{WithExec("echo", "printf")},
+			expected: config{Password: "from tag", Token: "tok", Count: 42},
+		},
+		{
+			name:     "Shell",
+			environ:  []string{"TOKEN=exec:echo abc | tr a-z A-Z"},
+			opts:     []Option{WithExec("echo 'from tag'", "echo abc | tr a-z A-Z"), WithExecShell(true)},
+			expected: config{Password: "from tag", Token: "ABC"},
+		},
+		{
+			name:    "Shell Needs Whole Command",
+			environ: []string{"TOKEN=exec:echo hi; id -un"},
+			opts:    []Option{WithExec("echo", "echo 'from tag'"), WithExecShell(true)},
+			wantErr: `command "echo hi; id -un" is not allowed`,
+		},
+		{
+			name:     "Prefix Literal When Disabled",
+			environ:  []string{"DB_PASSWORD=x", "TOKEN=exec:echo tok"},
+			expected: config{Password: "x", Token: "exec:echo tok"},
+		},
+		{
+			name:    "Tag Requires Opt In",
+			wantErr: "exec tag requires WithExec",
+		},
+		{
+			name:    "Not Allowed",
+			environ: []string{"TOKEN=exec:cat /etc/passwd"},
+			opts:    []Option{WithExec("echo")},
+			wantErr: "command cat is not allowed",
+		},
+		{
+			name:    "Failure",
+			environ: []string{"TOKEN=exec:sh -c 'echo denied >&2; exit 3'"},
+			opts:    []Option{WithExec("echo", "sh")},
+			wantErr: "command sh failed: exit status 3: denied",
+		},
+		{
+			name:    "Timeout",
+			environ: []string{"TOKEN=exec:sleep 5"},
+			opts:    []Option{WithExec("echo", "sleep"), WithExecTimeout(50 * time.Millisecond)},
+			wantErr: "command sleep timed out after 50ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{
+				WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)),
+				WithAutoLoadEnv(false),
+				WithEnviron(tt.environ),
+			}, tt.opts...)
+
+			var cfg config
+			err := NewLoader(opts...).Load(&cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg != tt.expected {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		wantErr  bool
+	}{
+		{input: "pass show db/password", expected: []string{"pass", "show", "db/password"}},
+		{input: "  aws   configure\texport-credentials ", expected: []string{"aws", "configure", "export-credentials"}},
+		{input: `helper 'single $quoted' "double \"quoted\"" esc\ aped ''`, expected: []string{"helper", "single $quoted", `double "quoted"`, "esc aped", ""}},
+		{input: "", expected: nil},
+		{input: "helper 'open", wantErr: true},
+		{input: `helper \`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := splitCommand(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("splitCommand() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestExecValuesOnlyFromEnv checks that exec: values in files are never run
+func TestExecValuesOnlyFromEnv(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("TOKEN=exec:echo tok\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg struct {
+		Token string `env:"TOKEN"`
+	}
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron(nil),
+		WithEnvFiles(envFile),
+		WithExec("echo"),
+	)
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Token != "exec:echo tok" {
+		t.Errorf("Token = %q, want the value kept literally", cfg.Token)
+	}
+}
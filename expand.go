@@ -0,0 +1,218 @@
+package enfl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lookupFunc resolves a variable name for expandVars
+type lookupFunc func(name string) (value string, set bool, err error)
+
+// expandVars expands variable references in s:
+//
+//	$NAME, ${NAME}      value of NAME, empty if unset
+//	${NAME:-fallback}   fallback if NAME is unset or empty (${NAME-fallback}: only if unset)
+//	${NAME:?message}    error if NAME is unset or empty (${NAME?message}: only if unset)
+//	$$                  a literal $
+func expandVars(s string, lookup lookupFunc) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		switch next := s[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := matchBrace(s, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference %q", s[i:])
+			}
+			value, err := expandBraced(s[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+		case isNameStart(next):
+			end := i + 2
+			for end < len(s) && isNameChar(s[end]) {
+				end++
+			}
+			value, _, err := lookup(s[i+1 : end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end - 1
+		default:
+			// A lone $ is kept as is
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+// expandBraced expands the inside of a ${...} reference
+func expandBraced(expr string, lookup lookupFunc) (string, error) {
+	n := 0
+	for n < len(expr) && (isNameStart(expr[n]) || (n > 0 && isNameChar(expr[n]))) {
+		n++
+	}
+	if n == 0 {
+		return "", fmt.Errorf("invalid variable reference ${%s}", expr)
+	}
+
+	name, op := expr[:n], expr[n:]
+	value, set, err := lookup(name)
+	if err != nil {
+		return "", err
+	}
+
+	// ":-" and ":?" also treat an empty value as missing
+	colon := strings.HasPrefix(op, ":")
+	missing := !set || (colon && value == "")
+	word := strings.TrimPrefix(op, ":")
+
+	switch {
+	case op == "":
+		return value, nil
+	case strings.HasPrefix(word, "-"):
+		if missing {
+			return expandVars(word[1:], lookup)
+		}
+		return value, nil
+	case strings.HasPrefix(word, "?"):
+		if !missing {
+			return value, nil
+		}
+		msg, err := expandVars(word[1:], lookup)
+		if err != nil {
+			return "", err
+		}
+		if msg == "" {
+			msg = "not set"
+		}
+		return "", fmt.Errorf("%s: %s", name, msg)
+	default:
+		return "", fmt.Errorf("invalid variable reference ${%s}", expr)
+	}
+}
+
+// matchBrace returns the index of the '}' closing the '{' at open, or -1
+func matchBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+// dotenvResolver expands the values of one dotenv file lazily so references to other keys
+// of the file resolve in any order and cycles are detected. Keys the file does not define
+// resolve against the values of previous files
+type dotenvResolver struct {
+	entries   map[string]dotenvEntry
+	values    map[string]string
+	previous  map[string]string
+	lookupEnv func(string) (string, bool)
+	resolving []string
+}
+
+func newDotenvResolver(entries map[string]dotenvEntry, previous map[string]string, lookupEnv func(string) (string, bool)) *dotenvResolver {
+	return &dotenvResolver{
+		entries:   entries,
+		values:    make(map[string]string, len(entries)),
+		previous:  previous,
+		lookupEnv: lookupEnv,
+	}
+}
+
+// lookup resolves a reference, the real environment wins over .env values like it does for fields
+func (r *dotenvResolver) lookup(name string) (string, bool, error) {
+	if value, ok := r.lookupEnv(name); ok {
+		return value, true, nil
+	}
+	// A key referencing itself, like PATH=${PATH}:/opt/bin, extends the value of previous files
+	if previous, ok := r.previous[name]; ok {
+		if _, defined := r.entries[name]; !defined || r.isResolving(name) {
+			return previous, true, nil
+		}
+	}
+	return r.resolve(name)
+}
+
+func (r *dotenvResolver) isResolving(name string) bool {
+	for _, resolving := range r.resolving {
+		if resolving == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the expanded .env value of name
+func (r *dotenvResolver) resolve(name string) (string, bool, error) {
+	if value, ok := r.values[name]; ok {
+		return value, true, nil
+	}
+	entry, ok := r.entries[name]
+	if !ok {
+		return "", false, nil
+	}
+
+	for i, resolving := range r.resolving {
+		if resolving == name {
+			cycle := append(append([]string(nil), r.resolving[i:]...), name)
+			return "", false, fmt.Errorf("variable cycle %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	value := entry.value
+	if entry.expand {
+		r.resolving = append(r.resolving, name)
+		expanded, err := expandVars(value, r.lookup)
+		r.resolving = r.resolving[:len(r.resolving)-1]
+		if err != nil {
+			return "", false, err
+		}
+		value = expanded
+	}
+
+	r.values[name] = value
+	return value, true, nil
+}
+
+// resolveAll expands every entry in order and returns the final values
+func (r *dotenvResolver) resolveAll(order []string) (map[string]string, error) {
+	for _, name := range order {
+		if _, _, err := r.resolve(name); err != nil {
+			entry := r.entries[name]
+			return nil, fmt.Errorf("%s:%d: %w", entry.file, entry.line, err)
+		}
+	}
+	return r.values, nil
+}
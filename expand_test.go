@@ -0,0 +1,159 @@
+package enfl
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandVars(t *testing.T) {
+	vars := map[string]string{"HOME": "/home/app", "EMPTY": "", "NAME": "svc"}
+	lookup := func(name string) (string, bool, error) {
+		value, ok := vars[name]
+		return value, ok, nil
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  string
+	}{
+		{name: "Plain", input: "no variables", expected: "no variables"},
+		{name: "Braced", input: "${HOME}/.cache", expected: "/home/app/.cache"},
+		{name: "Bare", input: "$HOME/$NAME.log", expected: "/home/app/svc.log"},
+		{name: "Unset", input: "[${MISSING}]", expected: "[]"},
+		{name: "Dollar Escape", input: "pa$$word costs $5", expected: "pa$word costs $5"},
+		{name: "Fallback Unset", input: "${MISSING:-/tmp}", expected: "/tmp"},
+		{name: "Fallback Empty", input: "${EMPTY:-/tmp}", expected: "/tmp"},
+		{name: "Fallback Only Unset", input: "${EMPTY-/tmp}", expected: ""},
+		{name: "Nested Fallback", input: "${MISSING:-${HOME}/data}", expected: "/home/app/data"},
+		{name: "Required Set", input: "${NAME:?name is required}", expected: "svc"},
+		{name: "Required Unset", input: "${MISSING:?must be set}", wantErr: "MISSING: must be set"},
+		{name: "Required Empty", input: "${EMPTY:?}", wantErr: "EMPTY: not set"},
+		{name: "Unterminated", input: "${HOME", wantErr: "unterminated"},
+		{name: "Invalid Name", input: "${1X}", wantErr: "invalid variable reference"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandVars(tt.input, lookup)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandVars() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandVars() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expandVars() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDotenvInterpolation(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	base := writeFile("base.env", "DB_HOST=db.internal\nDB_PORT=5432\n")
+	app := writeFile("app.env", strings.Join([]string{
+		`DB_URL="postgres://${DB_USER:-app}@${DB_HOST}:$DB_PORT/app"`,
+		`CACHE_DIR=${HOME}/.cache/app`,
+		`LITERAL='${DB_HOST}'`,
+		`PRICE=$$5`,
+		`PASSWORD="x\$y"`,
+		`TOKEN=a\$b`,
+	}, "\n"))
+	cycle := writeFile("cycle.env", "A=${B}\nB=x${A}\n")
+
+	type config struct {
+		DBURL    string `env:"DB_URL"`
+		CacheDir string `env:"CACHE_DIR"`
+		Literal  string `env:"LITERAL"`
+		Price    string `env:"PRICE"`
+		Password string `env:"PASSWORD"`
+		Token    string `env:"TOKEN"`
+		LogDir   string `env:"LOG_DIR" default:"${CACHE_DIR}/logs"`
+	}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron([]string{"HOME=/home/app", "DB_HOST=db.prod"}),
+		WithEnvFiles(base, app),
+	)
+
+	var cfg config
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	expected := config{
+		DBURL:    "postgres://app@db.prod:5432/app",
+		CacheDir: "/home/app/.cache/app",
+		Literal:  "${DB_HOST}",
+		Price:    "$5",
+		Password: "x$y",
+		Token:    "a$b",
+		LogDir:   "/home/app/.cache/app/logs",
+	}
+	if cfg != expected {
+		t.Errorf("Load() = %+v, want %+v", cfg, expected)
+	}
+
+	l = NewLoader(
+		WithFlagSet(flag.NewFlagSet("cycle", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron(nil),
+		WithEnvFiles(cycle),
+	)
+	err := l.Load(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "variable cycle A -> B -> A") {
+		t.Errorf("Load() error = %v, want variable cycle", err)
+	}
+}
+
+func TestDotenvInterpolationAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, ".env")
+	second := filepath.Join(dir, ".env.local")
+	if err := os.WriteFile(first, []byte("PATH_LIST=/usr/bin\nNAME=app\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("PATH_LIST=${PATH_LIST}:/opt/bin\nLABEL=${NAME}-${PATH_LIST}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type config struct {
+		PathList string `env:"PATH_LIST"`
+		Name     string `env:"NAME"`
+		Label    string `env:"LABEL"`
+	}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron(nil),
+		WithEnvFiles(first, second),
+	)
+
+	var cfg config
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	expected := config{PathList: "/usr/bin:/opt/bin", Name: "app", Label: "app-/usr/bin:/opt/bin"}
+	if cfg != expected {
+		t.Errorf("Load() = %+v, want %+v", cfg, expected)
+	}
+}
@@ -0,0 +1,41 @@
+package enfl
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// openFile opens name from fsys, or from the operating system when fsys is nil
+func openFile(fsys fs.FS, name string) (fs.File, error) {
+	if fsys == nil {
+		return os.Open(name)
+	}
+	return fsys.Open(fsPath(name))
+}
+
+// fileExists reports whether name exists in fsys, or on the operating system when fsys is nil
+func fileExists(fsys fs.FS, name string) bool {
+	var err error
+	if fsys == nil {
+		_, err = os.Stat(name)
+	} else {
+		_, err = fs.Stat(fsys, fsPath(name))
+	}
+	return err == nil
+}
+
+// joinPath joins path elements with the separator fsys expects
+func joinPath(fsys fs.FS, elem ...string) string {
+	if fsys == nil {
+		return filepath.Join(elem...)
+	}
+	return path.Join(elem...)
+}
+
+// fsPath turns an operating system style path such as ./config/app.json into the
+// unrooted, slash separated form fs.FS requires
+func fsPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
@@ -0,0 +1,90 @@
+package enfl
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		".env":                 {Data: []byte("NAME=base\nPORT=8000\n")},
+		".env.production":      {Data: []byte("PORT=9000\n")},
+		"config/.env":          {Data: []byte("REGION=eu\n")},
+		"secrets/extra.env":    {Data: []byte("TOKEN=abc\n")},
+		"config/defaults.json": {Data: []byte(`{"database": {"host": "db.embedded"}}`)},
+	}
+
+	type config struct {
+		Name     string `env:"NAME"`
+		Port     int    `env:"PORT"`
+		Region   string `env:"REGION"`
+		Token    string `env:"TOKEN"`
+		Database struct {
+			Host string `env:"HOST"`
+		} `prefix:"DB_"`
+	}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithEnviron(nil),
+		WithEnvironment("production"),
+		WithEnvFS(fsys),
+		WithSearchDirs("./config", "."),
+		WithSearchParents(true), // ignored for an fs.FS
+		WithEnvFiles("secrets/extra.env"),
+		WithConfigFS(fsys),
+		WithJSONFile("./config/defaults.json"),
+	)
+
+	var cfg config
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var expected config
+	expected.Name = "base"
+	expected.Port = 9000
+	expected.Region = "eu"
+	expected.Token = "abc"
+	expected.Database.Host = "db.embedded"
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Load() = %+v, want %+v", cfg, expected)
+	}
+
+	wantFiles := []string{".env", ".env.production", "config/.env", "secrets/extra.env"}
+	if got := l.LoadedFiles(); !reflect.DeepEqual(got, wantFiles) {
+		t.Errorf("LoadedFiles() = %v, want %v", got, wantFiles)
+	}
+}
+
+func TestLoadFromFSMissingFile(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{name: "Env File", opt: WithEnvFiles("missing.env")},
+		{name: "Config File", opt: WithYAMLFile("missing.yaml")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoader(
+				WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)),
+				WithAutoLoadEnv(false),
+				WithEnviron(nil),
+				WithEnvFS(fstest.MapFS{}),
+				WithConfigFS(fstest.MapFS{}),
+				tt.opt,
+			)
+
+			var cfg struct {
+				Name string `env:"NAME"`
+			}
+			if err := l.Load(&cfg); err == nil {
+				t.Error("Load() error = nil, want missing file error")
+			}
+		})
+	}
+}
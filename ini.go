@@ -0,0 +1,121 @@
+package enfl
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// INIFileSource returns a Source reading an INI file from path. Sections map onto nested
+// structs and honour `ini` tags, so host under [database] fills Config.Database.Host
+func INIFileSource(path string) Source {
+	return &fileSource{path: path, tag: "ini", parse: parseINI}
+}
+
+// WithINIFile adds an INI file to the precedence chain, below .env files and above defaults
+func WithINIFile(path string) Option {
+	return func(l *Loader) {
+		l.fileSources = append(l.fileSources, INIFileSource(path))
+	}
+}
+
+// parseINI reads an INI file into flat dotted keys. Keys before the first section are top
+// level, [a.b] sections nest, and keys may be dotted themselves. Values are separated by
+// '=' or ':', may be double quoted, and continue on the next line after a trailing
+// backslash. Lines starting with ';' or '#' are comments, as is anything after " ;" or " #"
+// in an unquoted value
+func parseINI(r io.Reader, file string) (map[string]interface{}, error) {
+	lines, err := readLogicalLines(r, ";#")
+	if err != nil {
+		return nil, err
+	}
+
+	doc := make(map[string]interface{})
+	section := ""
+	for _, line := range lines {
+		text := strings.TrimSpace(line.text)
+
+		if strings.HasPrefix(text, "[") {
+			end := strings.IndexByte(text, ']')
+			if end < 0 {
+				return nil, &ParseError{File: file, Line: line.line, Msg: "unterminated section header"}
+			}
+			if rest := strings.TrimSpace(text[end+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+				return nil, &ParseError{File: file, Line: line.line, Msg: fmt.Sprintf("unexpected %q after section header", rest)}
+			}
+			section = strings.TrimSpace(text[1:end])
+			if section == "" {
+				return nil, &ParseError{File: file, Line: line.line, Msg: "empty section name"}
+			}
+			continue
+		}
+
+		sep := strings.IndexAny(text, "=:")
+		if sep < 0 {
+			return nil, &ParseError{File: file, Line: line.line, Msg: fmt.Sprintf("expected '=' or ':' after key %q", text)}
+		}
+		key := strings.TrimSpace(text[:sep])
+		if key == "" {
+			return nil, &ParseError{File: file, Line: line.line, Msg: "missing key"}
+		}
+
+		value, err := parseINIValue(strings.TrimSpace(text[sep+1:]))
+		if err != nil {
+			return nil, &ParseError{File: file, Line: line.line, Msg: err.Error()}
+		}
+		if section != "" {
+			key = section + "." + key
+		}
+		doc[key] = value
+	}
+	return doc, nil
+}
+
+// parseINIValue unquotes double quoted values and strips inline comments from bare ones.
+// Quoted values support the .properties escapes; bare values only decode \uXXXX so that
+// Windows paths survive untouched
+func parseINIValue(value string) (string, error) {
+	if strings.HasPrefix(value, `"`) {
+		end := closingINIQuote(value)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		if rest := strings.TrimSpace(value[end+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+			return "", fmt.Errorf("unexpected %q after quoted value", rest)
+		}
+		return unescapeProperties(value[1:end])
+	}
+
+	for i := 1; i < len(value); i++ {
+		if (value[i] == ';' || value[i] == '#') && (value[i-1] == ' ' || value[i-1] == '\t') {
+			value = strings.TrimSpace(value[:i])
+			break
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if strings.HasPrefix(value[i:], `\u`) {
+			if r, err := parseUnicodeEscape(value[i+2:]); err == nil {
+				b.WriteRune(r)
+				i += 5
+				continue
+			}
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String(), nil
+}
+
+// closingINIQuote returns the index of the quote ending the value opened at value[0]
+func closingINIQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,138 @@
+package enfl
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseINI(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]interface{}
+	}{
+		{
+			name:  "Sections",
+			input: "name = app\n\n[database]\nhost = db\nport: 5432\n\n[server.http]\ntimeout = 5s\n",
+			expected: map[string]interface{}{
+				"name":                "app",
+				"database.host":       "db",
+				"database.port":       "5432",
+				"server.http.timeout": "5s",
+			},
+		},
+		{
+			name:  "Comments",
+			input: "; comment\n# comment\n[main] ; trailing\nurl = http://example.com/#frag\nlevel = debug ; inline\nempty =\n",
+			expected: map[string]interface{}{
+				"main.url":   "http://example.com/#frag",
+				"main.level": "debug",
+				"main.empty": "",
+			},
+		},
+		{
+			name:  "Quoted Values",
+			input: "[main]\ngreeting = \"hello ; world\" ; comment\nescaped = \"tab\\there \\\"q\\\" \\u00e9\"\n",
+			expected: map[string]interface{}{
+				"main.greeting": "hello ; world",
+				"main.escaped":  "tab\there \"q\" é",
+			},
+		},
+		{
+			name:  "Continuations And Escapes",
+			input: "[paths]\nwindows = C:\\users\\app\nlist = a, \\\n  b\nunicode = caf\\u00e9\n",
+			expected: map[string]interface{}{
+				"paths.windows": `C:\users\app`,
+				"paths.list":    "a, b",
+				"paths.unicode": "café",
+			},
+		},
+		{
+			name:  "Dotted Keys",
+			input: "[database]\npool.size = 10\n",
+			expected: map[string]interface{}{
+				"database.pool.size": "10",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseINI(strings.NewReader(tt.input), "app.ini")
+			if err != nil {
+				t.Fatalf("parseINI() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseINI() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseINIErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{name: "Unterminated Section", input: "a = 1\n[main\n", line: 2},
+		{name: "Empty Section", input: "[]\n", line: 1},
+		{name: "Missing Separator", input: "[main]\njust text\n", line: 2},
+		{name: "Missing Key", input: "= value\n", line: 1},
+		{name: "Unterminated Quote", input: "a = 1\nb = \"open\n", line: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseINI(strings.NewReader(tt.input), "app.ini")
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("parseINI() error = %v, want *ParseError", err)
+			}
+			if perr.Line != tt.line {
+				t.Errorf("parseINI() error line = %d, want %d (%v)", perr.Line, tt.line, err)
+			}
+		})
+	}
+}
+
+func TestINIFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.ini")
+	content := "name = from-ini\n\n[database]\nhost = db.internal\npool_size = 25\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type config struct {
+		Name     string `env:"NAME"`
+		Database struct {
+			Host     string `env:"HOST"`
+			MaxConns int    `env:"MAX_CONNS" ini:"pool_size"`
+		} `prefix:"DB_"`
+	}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron([]string{"DB_HOST=from-env"}),
+		WithINIFile(path),
+	)
+
+	var cfg config
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var expected config
+	expected.Name = "from-ini"
+	expected.Database.Host = "from-env"
+	expected.Database.MaxConns = 25
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Load() = %+v, want %+v", cfg, expected)
+	}
+}
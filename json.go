@@ -0,0 +1,55 @@
+package enfl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// JSONFileSource returns a Source reading a JSON object from path. Object keys follow the
+// nested struct layout and honour `json` tags, e.g. {"database": {"host": "db"}} fills
+// Config.Database.Host
+func JSONFileSource(path string) Source {
+	return &fileSource{path: path, tag: "json", parse: parseJSON}
+}
+
+// WithJSONFile adds a JSON file to the precedence chain, below .env files and above defaults
+func WithJSONFile(path string) Option {
+	return func(l *Loader) {
+		l.fileSources = append(l.fileSources, JSONFileSource(path))
+	}
+}
+
+// parseJSON decodes a JSON object keeping numbers as written
+func parseJSON(r io.Reader, file string) (map[string]interface{}, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, col := offsetPosition(data, syntaxErr.Offset)
+			return nil, &ParseError{File: file, Line: line, Column: col, Msg: syntaxErr.Error()}
+		}
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return doc, nil
+}
+
+// offsetPosition converts a byte offset into a 1-based line and column
+func offsetPosition(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
@@ -0,0 +1,74 @@
+package enfl
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type jsonTestConfig struct {
+	Name     string `env:"NAME" default:"app"`
+	Port     int    `env:"PORT" default:"8080"`
+	Database struct {
+		Host     string        `env:"HOST" default:"localhost"`
+		MaxConns int           `env:"MAX_CONNS" json:"pool_size"`
+		Timeout  time.Duration `env:"TIMEOUT"`
+	} `prefix:"DB_"`
+	Tags  []string `env:"TAGS"`
+	Ratio float64  `env:"RATIO"`
+	Debug bool     `env:"DEBUG"`
+	// Secret is never read from the file
+	Secret string `env:"SECRET" json:"-"`
+}
+
+func TestJSONFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{
+		"name": "from-json",
+		"port": 9000,
+		"database": {"host": "db.internal", "pool_size": 25, "timeout": "5s"},
+		"tags": ["a", "b"],
+		"ratio": 0.75,
+		"debug": true,
+		"secret": "from-json"
+	}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron([]string{"PORT=7000"}),
+		WithJSONFile(path),
+	)
+
+	var cfg jsonTestConfig
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	expected := jsonTestConfig{Name: "from-json", Port: 7000, Tags: []string{"a", "b"}, Ratio: 0.75, Debug: true}
+	expected.Database.Host = "db.internal"
+	expected.Database.MaxConns = 25
+	expected.Database.Timeout = 5 * time.Second
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Load() = %+v, want %+v", cfg, expected)
+	}
+}
+
+func TestParseJSONError(t *testing.T) {
+	_, err := parseJSON(strings.NewReader("{\n  \"a\": 1,\n  \"b\": ]\n}"), "config.json")
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("parseJSON() error = %v, want *ParseError", err)
+	}
+	if perr.Line != 3 {
+		t.Errorf("parseJSON() error line = %d, want 3 (%v)", perr.Line, err)
+	}
+}
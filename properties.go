@@ -0,0 +1,159 @@
+package enfl
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// PropertiesFileSource returns a Source reading a Java .properties file from path. Dotted
+// keys follow the nested struct layout and honour `properties` tags, so database.host
+// fills Config.Database.Host
+func PropertiesFileSource(path string) Source {
+	return &fileSource{path: path, tag: "properties", parse: parseProperties}
+}
+
+// WithPropertiesFile adds a .properties file to the precedence chain, below .env files and above defaults
+func WithPropertiesFile(path string) Option {
+	return func(l *Loader) {
+		l.fileSources = append(l.fileSources, PropertiesFileSource(path))
+	}
+}
+
+// logicalLine is a line with continuations joined, numbered by its first physical line
+type logicalLine struct {
+	text string
+	line int
+}
+
+// readLogicalLines splits r into lines, joining lines that end in an odd number of
+// backslashes with the next one. Blank lines and lines starting with one of comments
+// are dropped
+func readLogicalLines(r io.Reader, comments string) ([]logicalLine, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	physical := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	var lines []logicalLine
+	for i := 0; i < len(physical); i++ {
+		text := strings.TrimLeft(physical[i], " \t\f")
+		if text == "" || strings.ContainsRune(comments, rune(text[0])) {
+			continue
+		}
+
+		start := i + 1
+		for continues(text) && i+1 < len(physical) {
+			i++
+			text = text[:len(text)-1] + strings.TrimLeft(physical[i], " \t\f")
+		}
+		if continues(text) {
+			text = text[:len(text)-1] // a continuation at end of file joins nothing
+		}
+		lines = append(lines, logicalLine{text: text, line: start})
+	}
+	return lines, nil
+}
+
+// continues reports whether line ends in an unescaped backslash
+func continues(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// parseProperties reads a .properties file into flat dotted keys. Keys end at the first
+// unescaped '=', ':' or whitespace; values support \t, \n, \r, \f and \uXXXX escapes, any
+// other escaped character stands for itself. Later keys override earlier ones
+func parseProperties(r io.Reader, file string) (map[string]interface{}, error) {
+	lines, err := readLogicalLines(r, "#!")
+	if err != nil {
+		return nil, err
+	}
+
+	doc := make(map[string]interface{})
+	for _, line := range lines {
+		end := propertiesKeyEnd(line.text)
+		rawKey := line.text[:end]
+		rest := strings.TrimLeft(line.text[end:], " \t\f")
+		if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+			rest = strings.TrimLeft(rest[1:], " \t\f")
+		}
+
+		key, err := unescapeProperties(rawKey)
+		if err != nil {
+			return nil, &ParseError{File: file, Line: line.line, Msg: err.Error()}
+		}
+		value, err := unescapeProperties(rest)
+		if err != nil {
+			return nil, &ParseError{File: file, Line: line.line, Msg: err.Error()}
+		}
+		doc[key] = value
+	}
+	return doc, nil
+}
+
+// propertiesKeyEnd returns the offset of the first unescaped separator in line
+func propertiesKeyEnd(line string) int {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':', ' ', '\t', '\f':
+			return i
+		}
+	}
+	return len(line)
+}
+
+func unescapeProperties(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, err := parseUnicodeEscape(s[i+1:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+			i += 4
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// parseUnicodeEscape decodes the four hex digits following \u
+func parseUnicodeEscape(s string) (rune, error) {
+	if len(s) < 4 {
+		return 0, fmt.Errorf("malformed \\u escape")
+	}
+	code, err := strconv.ParseUint(s[:4], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return 0, fmt.Errorf("malformed \\u%s escape", s[:4])
+	}
+	return rune(code), nil
+}
@@ -0,0 +1,125 @@
+package enfl
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]interface{}
+	}{
+		{
+			name:  "Separators",
+			input: "a=1\nb: 2\nc 3\nd  =  4  \ne\nf=x=y:z\n",
+			expected: map[string]interface{}{
+				"a": "1",
+				"b": "2",
+				"c": "3",
+				"d": "4  ",
+				"e": "",
+				"f": "x=y:z",
+			},
+		},
+		{
+			name:  "Comments And Blank Lines",
+			input: "# comment\n! also a comment\n\n   \nkey = value # not a comment\n",
+			expected: map[string]interface{}{
+				"key": "value # not a comment",
+			},
+		},
+		{
+			name:  "Continuations",
+			input: "list = a, \\\n       b, \\\n       c\npath = C:\\\\dir\\\\\nnext = 1\n",
+			expected: map[string]interface{}{
+				"list": "a, b, c",
+				"path": `C:\dir\`,
+				"next": "1",
+			},
+		},
+		{
+			name:  "Escapes",
+			input: "greeting = caf\\u00e9\\tbar\\nbaz\nkey\\ with\\ spaces = v\\=x\nescaped\\:colon = 1\n",
+			expected: map[string]interface{}{
+				"greeting":        "café\tbar\nbaz",
+				"key with spaces": "v=x",
+				"escaped:colon":   "1",
+			},
+		},
+		{
+			name:  "Dotted Keys And Overrides",
+			input: "database.host = db\ndatabase.port = 5432\ndatabase.host = override\n",
+			expected: map[string]interface{}{
+				"database.host": "override",
+				"database.port": "5432",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProperties(strings.NewReader(tt.input), "app.properties")
+			if err != nil {
+				t.Fatalf("parseProperties() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseProperties() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParsePropertiesError(t *testing.T) {
+	_, err := parseProperties(strings.NewReader("a = 1\nb = \\u12\n"), "app.properties")
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("parseProperties() error = %v, want *ParseError", err)
+	}
+	if perr.Line != 2 {
+		t.Errorf("parseProperties() error line = %d, want 2 (%v)", perr.Line, err)
+	}
+}
+
+func TestPropertiesFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.properties")
+	content := "port=9000\nserver.read-timeout=5s\nserver.hosts=a,\\\n  b\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type config struct {
+		Port   int `env:"PORT"`
+		Server struct {
+			ReadTimeout time.Duration `env:"READ_TIMEOUT"`
+			Hosts       []string      `env:"HOSTS" properties:"hosts"`
+		} `prefix:"SERVER_"`
+	}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron(nil),
+		WithPropertiesFile(path),
+	)
+
+	var cfg config
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var expected config
+	expected.Port = 9000
+	expected.Server.ReadTimeout = 5 * time.Second
+	expected.Server.Hosts = []string{"a", "b"}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Load() = %+v, want %+v", cfg, expected)
+	}
+}
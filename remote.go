@@ -0,0 +1,293 @@
+package enfl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RemoteOption configures a remote source
+type RemoteOption func(*remoteSource)
+
+// RemoteTimeout limits how long fetching the document may take, 10 seconds by default
+func RemoteTimeout(timeout time.Duration) RemoteOption {
+	return func(s *remoteSource) {
+		s.timeout = timeout
+	}
+}
+
+// RemoteCacheFile keeps the last fetched document and its ETag in path. The cache makes
+// requests conditional and is used instead when the service cannot be reached
+func RemoteCacheFile(path string) RemoteOption {
+	return func(s *remoteSource) {
+		s.cacheFile = path
+	}
+}
+
+// RemoteHeader adds a header to every request, e.g. an Authorization token
+func RemoteHeader(key, value string) RemoteOption {
+	return func(s *remoteSource) {
+		s.header.Add(key, value)
+	}
+}
+
+// RemoteHTTPClient sets the client used for requests, http.DefaultClient by default
+func RemoteHTTPClient(client *http.Client) RemoteOption {
+	return func(s *remoteSource) {
+		s.client = client
+	}
+}
+
+// RemoteFormat forces the document format, "json" or "dotenv". By default documents served
+// as application/json or starting with '{' are JSON and everything else is dotenv
+func RemoteFormat(format string) RemoteOption {
+	return func(s *remoteSource) {
+		s.format = format
+	}
+}
+
+// RemoteSource returns a Source fetching a JSON or dotenv document from url once per Load.
+// JSON documents follow the nested struct layout like JSONFileSource, dotenv documents are
+// keyed by environment variable name like DotenvSource
+func RemoteSource(url string, opts ...RemoteOption) Source {
+	s := &remoteSource{
+		url:     url,
+		timeout: 10 * time.Second,
+		client:  http.DefaultClient,
+		header:  make(http.Header),
+		lookup:  os.LookupEnv,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithRemote adds a remote document to the precedence chain, below environment variables and
+// config directories and above .env and configuration files; when several are added the last one wins
+func WithRemote(url string, opts ...RemoteOption) Option {
+	return func(l *Loader) {
+		l.remotes = append(l.remotes, RemoteSource(url, opts...))
+	}
+}
+
+type remoteSource struct {
+	url       string
+	timeout   time.Duration
+	cacheFile string
+	header    http.Header
+	client    *http.Client
+	format    string
+	lookup    func(string) (string, bool)
+
+	cached *remoteDocument // last document fetched or read from the cache file
+	tree   *treeSource     // values of a JSON document
+	values map[string]string
+}
+
+// remoteDocument is a fetched document as stored in the cache file
+type remoteDocument struct {
+	ETag        string `json:"etag,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+func (s *remoteSource) bind(l *Loader) { s.lookup = l.lookupEnv }
+
+func (s *remoteSource) Name() string {
+	if u, err := url.Parse(s.url); err == nil {
+		return u.Redacted()
+	}
+	return s.url
+}
+
+func (s *remoteSource) Prepare() error {
+	s.tree, s.values = nil, nil
+	if s.cached == nil && s.cacheFile != "" {
+		s.cached = readRemoteCache(s.cacheFile)
+	}
+
+	doc, err := s.fetch()
+	if err == nil {
+		err = s.use(doc)
+	}
+	if err == nil {
+		if doc != s.cached {
+			s.cached = doc
+			if s.cacheFile != "" {
+				if cacheErr := writeRemoteCache(s.cacheFile, doc); cacheErr != nil {
+					return cacheErr
+				}
+			}
+		}
+		return nil
+	}
+
+	// Fall back to the last good copy
+	if s.cached != nil && s.use(s.cached) == nil {
+		return nil
+	}
+	return err
+}
+
+// fetch requests the document, returning the cached copy when the server answers 304 Not Modified
+func (s *remoteSource) fetch() (*remoteDocument, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote url: %w", err)
+	}
+	for key, values := range s.header {
+		req.Header[key] = values
+	}
+	if s.cached != nil && s.cached.ETag != "" {
+		req.Header.Set("If-None-Match", s.cached.ETag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && s.cached != nil:
+		return s.cached, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, s.Name())
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", s.Name(), err)
+	}
+	return &remoteDocument{
+		ETag:        resp.Header.Get("ETag"),
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(body),
+	}, nil
+}
+
+// use parses doc and serves its values
+func (s *remoteSource) use(doc *remoteDocument) error {
+	name := s.Name()
+	switch format := s.documentFormat(doc); format {
+	case "json":
+		parsed, err := parseJSON(strings.NewReader(doc.Body), name)
+		if err != nil {
+			return err
+		}
+		s.tree = newTreeSource("json", parsed)
+		return nil
+	case "dotenv":
+	default:
+		return fmt.Errorf("unsupported remote format %q", format)
+	}
+
+	entries, err := parseDotenv(strings.NewReader(doc.Body), name)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]dotenvEntry, len(entries))
+	order := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if _, exists := byKey[entry.key]; !exists {
+			order = append(order, entry.key)
+		}
+		byKey[entry.key] = entry
+	}
+	values, err := newDotenvResolver(byKey, nil, s.lookup).resolveAll(order)
+	if err != nil {
+		return err
+	}
+	s.values = values
+	return nil
+}
+
+func (s *remoteSource) documentFormat(doc *remoteDocument) string {
+	if s.format != "" {
+		return s.format
+	}
+	if mediaType, _, err := mime.ParseMediaType(doc.ContentType); err == nil {
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return "json"
+		}
+	}
+	if strings.HasPrefix(strings.TrimSpace(doc.Body), "{") {
+		return "json"
+	}
+	return "dotenv"
+}
+
+func (s *remoteSource) Lookup(key Key) (string, bool, error) {
+	if s.tree != nil {
+		value, ok := s.tree.lookup(key)
+		return value, ok, nil
+	}
+	if key.Env == "" {
+		return "", false, nil
+	}
+	value, ok := s.values[key.Env]
+	return value, ok, nil
+}
+
+func (s *remoteSource) Keys() []string {
+	if s.tree != nil {
+		return s.tree.keys()
+	}
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// readRemoteCache returns the cached document, or nil when there is no usable cache
+func readRemoteCache(path string) *remoteDocument {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var doc remoteDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	return &doc
+}
+
+// writeRemoteCache replaces the cache file atomically so a crash never leaves half a document
+func writeRemoteCache(path string, doc *remoteDocument) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write remote cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, bytes.NewReader(data)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write remote cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write remote cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write remote cache: %w", err)
+	}
+	return nil
+}
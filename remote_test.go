@@ -0,0 +1,206 @@
+package enfl
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type remoteTestConfig struct {
+	Port     int `env:"PORT" default:"8080"`
+	Database struct {
+		Host string `env:"HOST"`
+	} `prefix:"DB_"`
+}
+
+func loadRemote(t *testing.T, environ []string, opts ...Option) (remoteTestConfig, error) {
+	t.Helper()
+	opts = append([]Option{
+		WithFlagSet(flag.NewFlagSet(t.Name(), flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron(environ),
+	}, opts...)
+
+	var cfg remoteTestConfig
+	err := NewLoader(opts...).Load(&cfg)
+	return cfg, err
+}
+
+func TestRemoteSourceFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        []RemoteOption
+	}{
+		{
+			name:        "JSON",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"port": 9000, "database": {"host": "db.remote"}}`,
+		},
+		{
+			name:        "Dotenv",
+			contentType: "text/plain",
+			body:        "PORT=9000\nDB_HOST=db.${REGION}\n",
+		},
+		{
+			name: "Sniffed JSON",
+			body: `{"port": 9000, "database": {"host": "db.remote"}}`,
+		},
+		{
+			name:        "Forced Dotenv",
+			contentType: "application/octet-stream",
+			body:        "PORT=9000\nDB_HOST=db.remote\n",
+			opts:        []RemoteOption{RemoteFormat("dotenv")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			opts := append([]RemoteOption{RemoteHeader("Authorization", "Bearer token")}, tt.opts...)
+			cfg, err := loadRemote(t, []string{"REGION=remote"}, WithRemote(srv.URL, opts...))
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Port != 9000 || cfg.Database.Host != "db.remote" {
+				t.Errorf("Load() = %+v, want port 9000 and host db.remote", cfg)
+			}
+		})
+	}
+}
+
+func TestRemoteSourcePrecedence(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("PORT=9000\nDB_HOST=db.remote\n"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"port": 1, "database": {"host": "db.file"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(dir, ".env")
+	if err := os.WriteFile(envFile, []byte("DB_HOST=db.dotenv\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadRemote(t, []string{"PORT=7000"}, WithRemote(srv.URL), WithJSONFile(path), WithEnvFiles(envFile))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != 7000 || cfg.Database.Host != "db.remote" {
+		t.Errorf("Load() = %+v, want env port 7000 and remote host", cfg)
+	}
+}
+
+func TestRemoteSourceCache(t *testing.T) {
+	var requests, notModified atomic.Int32
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"port": 9000}`))
+	}))
+	defer srv.Close()
+
+	cache := filepath.Join(t.TempDir(), "remote.cache")
+	src := RemoteSource(srv.URL, RemoteCacheFile(cache))
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron(nil),
+		WithSources(EnvSource(), src, DefaultSource()),
+	)
+
+	for i := 0; i < 2; i++ {
+		var cfg remoteTestConfig
+		if err := l.Load(&cfg); err != nil {
+			t.Fatalf("Load() #%d error = %v", i+1, err)
+		}
+		if cfg.Port != 9000 {
+			t.Errorf("Load() #%d port = %d, want 9000", i+1, cfg.Port)
+		}
+	}
+	if requests.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("requests = %d, not modified = %d, want 2 and 1", requests.Load(), notModified.Load())
+	}
+
+	// A fresh source, as after a restart, falls back to the cache file while the service is down
+	down.Store(true)
+	cfg, err := loadRemote(t, nil, WithRemote(srv.URL, RemoteCacheFile(cache)))
+	if err != nil {
+		t.Fatalf("Load() with service down error = %v", err)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("Load() with service down port = %d, want cached 9000", cfg.Port)
+	}
+}
+
+func TestRemoteSourceErrors(t *testing.T) {
+	block := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer slow.Close()
+	defer close(block)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	invalid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"port": `))
+	}))
+	defer invalid.Close()
+
+	tests := []struct {
+		name    string
+		opt     Option
+		wantErr string
+	}{
+		{name: "Timeout", opt: WithRemote(slow.URL, RemoteTimeout(50*time.Millisecond)), wantErr: "deadline exceeded"},
+		{name: "Status", opt: WithRemote(failing.URL), wantErr: "500"},
+		{name: "Invalid Document", opt: WithRemote(invalid.URL), wantErr: "unexpected EOF"},
+		{name: "No Cache To Fall Back To", opt: WithRemote(failing.URL, RemoteCacheFile(filepath.Join(t.TempDir(), "missing"))), wantErr: "500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadRemote(t, nil, tt.opt)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package enfl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// SecretResolver resolves references such as secret://vault/db/creds#password to the
+// secret they point at. For secret:// references ref.Host names the provider; ref.Path and
+// ref.Fragment locate the secret within it
+type SecretResolver interface {
+	Resolve(ref *url.URL) (string, error)
+}
+
+// SecretResolverFunc adapts a function to a SecretResolver
+type SecretResolverFunc func(ref *url.URL) (string, error)
+
+// Resolve calls f(ref)
+func (f SecretResolverFunc) Resolve(ref *url.URL) (string, error) {
+	return f(ref)
+}
+
+// WithSecretResolver registers r for secret://name/... references and for values using the
+// name as their URL scheme, e.g. "file" for file:///run/secrets/db. Values with other
+// schemes are left untouched, while a secret:// reference to an unregistered provider is an error.
+// Only environment variables, .env files and default tags are resolved
+func WithSecretResolver(name string, r SecretResolver) Option {
+	return func(l *Loader) {
+		if l.secrets == nil {
+			l.secrets = make(map[string]SecretResolver)
+		}
+		l.secrets[name] = r
+	}
+}
+
+// resolveSecret replaces value with the secret it references, values that are no reference are returned as is
+func (l *Loader) resolveSecret(value string) (string, error) {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return value, nil
+	}
+	if scheme != "secret" && l.secrets[scheme] == nil {
+		return value, nil
+	}
+
+	ref, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret reference: %w", err)
+	}
+	provider := ref.Scheme
+	if provider == "secret" {
+		provider = ref.Host
+	}
+	resolver, ok := l.secrets[provider]
+	if !ok {
+		return "", fmt.Errorf("no secret resolver registered for %q", provider)
+	}
+	return resolver.Resolve(ref)
+}
+
+// FileResolver returns a SecretResolver reading the file at the reference path, e.g.
+// file:///run/secrets/db or secret://files/run/secrets/db when registered as "files".
+// Surrounding whitespace is trimmed. A fragment selects a key from a file holding a JSON
+// object or dotenv assignments: file:///run/secrets/db.json#password
+func FileResolver() SecretResolver {
+	return SecretResolverFunc(func(ref *url.URL) (string, error) {
+		data, err := os.ReadFile(ref.Path)
+		if err != nil {
+			return "", err
+		}
+		content := strings.TrimSpace(string(data))
+		if ref.Fragment == "" {
+			return content, nil
+		}
+		return secretKey(content, ref.Path, ref.Fragment)
+	})
+}
+
+// secretKey picks key from content holding a JSON object or dotenv assignments
+func secretKey(content, file, key string) (string, error) {
+	if strings.HasPrefix(content, "{") {
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(content), &values); err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if value, ok := formatScalar(values[key]); ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("key %s not found in %s", key, file)
+	}
+
+	entries, err := parseDotenv(strings.NewReader(content), file)
+	if err != nil {
+		return "", err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].key == key {
+			return entries[i].value, nil
+		}
+	}
+	return "", fmt.Errorf("key %s not found in %s", key, file)
+}
+
+// MapResolver returns an in-memory SecretResolver for tests. Secrets are keyed by the
+// reference path without its leading slash plus "#key" when a fragment is given, so
+// secret://vault/db/creds#password reads secrets["db/creds#password"]
+func MapResolver(secrets map[string]string) SecretResolver {
+	return SecretResolverFunc(func(ref *url.URL) (string, error) {
+		name := strings.TrimPrefix(ref.Path, "/")
+		if ref.Fragment != "" {
+			name += "#" + ref.Fragment
+		}
+		value, ok := secrets[name]
+		if !ok {
+			return "", fmt.Errorf("secret %s not found", name)
+		}
+		return value, nil
+	})
+}
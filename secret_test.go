@@ -0,0 +1,174 @@
+package enfl
+
+import (
+	"errors"
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretResolution(t *testing.T) {
+	type config struct {
+		Password string `env:"DB_PASSWORD"`
+		Token    string `env:"TOKEN" default:"secret://vault/api#token"`
+		Port     int    `env:"PORT"`
+		Database string `env:"DATABASE_URL"`
+	}
+
+	vault := MapResolver(map[string]string{
+		"db/creds#password": "s3cret",
+		"api#token":         "tok",
+		"ports/http":        "9000",
+	})
+
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "password")
+	jsonFile := filepath.Join(dir, "creds.json")
+	dotenvFile := filepath.Join(dir, "creds.env")
+	for path, content := range map[string]string{
+		plain:      "from-file\n",
+		jsonFile:   `{"password": "from-json", "port": 9001}`,
+		dotenvFile: "PASSWORD=from-dotenv\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		environ  []string
+		expected config
+		wantErr  string
+	}{
+		{
+			name:     "Provider References",
+			environ:  []string{"DB_PASSWORD=secret://vault/db/creds#password", "PORT=secret://vault/ports/http"},
+			expected: config{Password: "s3cret", Token: "tok", Port: 9000},
+		},
+		{
+			name:     "File Scheme",
+			environ:  []string{"DB_PASSWORD=file://" + plain, "PORT=file://" + jsonFile + "#port"},
+			expected: config{Password: "from-file", Token: "tok", Port: 9001},
+		},
+		{
+			name:     "File Keys",
+			environ:  []string{"DB_PASSWORD=secret://files" + dotenvFile + "#PASSWORD", "TOKEN=file://" + jsonFile + "#password"},
+			expected: config{Password: "from-dotenv", Token: "from-json"},
+		},
+		{
+			name:     "Other Schemes Untouched",
+			environ:  []string{"DATABASE_URL=postgres://db:5432/app"},
+			expected: config{Token: "tok", Database: "postgres://db:5432/app"},
+		},
+		{
+			name:    "Unknown Provider",
+			environ: []string{"DB_PASSWORD=secret://ssm/db"},
+			wantErr: `no secret resolver registered for "ssm"`,
+		},
+		{
+			name:    "Missing Secret",
+			environ: []string{"DB_PASSWORD=secret://vault/db/other"},
+			wantErr: "secret db/other not found",
+		},
+		{
+			name:    "Missing Key",
+			environ: []string{"DB_PASSWORD=file://" + jsonFile + "#user"},
+			wantErr: "key user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoader(
+				WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)),
+				WithAutoLoadEnv(false),
+				WithEnviron(tt.environ),
+				WithSecretResolver("vault", vault),
+				WithSecretResolver("file", FileResolver()),
+				WithSecretResolver("files", FileResolver()),
+			)
+
+			var cfg config
+			err := l.Load(&cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg != tt.expected {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSecretResolverError(t *testing.T) {
+	errDenied := errors.New("permission denied")
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron([]string{"DB_PASSWORD=secret://vault/db"}),
+		WithSecretResolver("vault", SecretResolverFunc(func(ref *url.URL) (string, error) {
+			return "", errDenied
+		})),
+	)
+
+	var cfg struct {
+		Password string `env:"DB_PASSWORD"`
+	}
+	if err := l.Load(&cfg); !errors.Is(err, errDenied) {
+		t.Errorf("Load() error = %v, want %v", err, errDenied)
+	}
+}
+
+// TestSecretsOnlyFromEnvAndDefaults checks that references in files are not resolved, so a
+// configuration file or config server cannot read local files into fields
+func TestSecretsNotFromConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secret, []byte("s3cret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ref := "file://" + secret
+	envFile := filepath.Join(dir, ".env")
+	if err := os.WriteFile(envFile, []byte("FROM_DOTENV="+ref+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jsonFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"from_json": "`+ref+`"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg struct {
+		FromEnv     string `env:"FROM_ENV"`
+		FromDefault string `env:"FROM_DEFAULT" default:"secret://vault/db#password"`
+		FromDotenv  string `env:"FROM_DOTENV"`
+		FromJSON    string `env:"FROM_JSON"`
+	}
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron([]string{"FROM_ENV=" + ref}),
+		WithEnvFiles(envFile),
+		WithJSONFile(jsonFile),
+		WithSecretResolver("file", FileResolver()),
+		WithSecretResolver("vault", MapResolver(map[string]string{"db#password": "s3cret"})),
+	)
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.FromEnv != "s3cret" || cfg.FromDefault != "s3cret" || cfg.FromDotenv != "s3cret" {
+		t.Errorf("FromEnv = %q, FromDefault = %q, FromDotenv = %q, want all resolved", cfg.FromEnv, cfg.FromDefault, cfg.FromDotenv)
+	}
+	if cfg.FromJSON != ref {
+		t.Errorf("FromJSON = %q, want the reference kept", cfg.FromJSON)
+	}
+}
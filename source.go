@@ -0,0 +1,280 @@
+package enfl
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Source provides raw configuration values for struct fields
+type Source interface {
+	// Name identifies the source in error messages
+	Name() string
+	// Lookup returns the value stored under key and whether the source has it; a value
+	// set to "" should be reported as found so the loader can honour WithEmptyIsSet
+	Lookup(key Key) (string, bool, error)
+}
+
+// Enumerator is implemented by sources that can list the keys they hold
+type Enumerator interface {
+	Keys() []string
+}
+
+// Preparer is implemented by sources that read their data once per Load
+type Preparer interface {
+	Prepare() error
+}
+
+// Key identifies a struct field under every naming scheme a Source may use
+type Key struct {
+	Field string            // Go field name, e.g. Host
+	Env   string            // environment variable name, e.g. DB_HOST
+	Flag  string            // command line flag name, e.g. host
+	Path  []string          // Go field names from the root struct, e.g. [Database Host]
+	Tag   reflect.StructTag // struct tag of the field
+
+	fields []reflect.StructField // struct fields along Path
+}
+
+// PathFor returns Path with every segment renamed by the named struct tag when the
+// field has one, so PathFor("json") honours `json:"host"` the way encoding/json does.
+// It returns nil when a field on the path is excluded with a "-" tag
+func (k Key) PathFor(tag string) []string {
+	path := make([]string, len(k.Path))
+	copy(path, k.Path)
+	for i, field := range k.fields {
+		value := field.Tag.Get(tag)
+		if value == "-" {
+			return nil
+		}
+		if name, _, _ := strings.Cut(value, ","); name != "" {
+			path[i] = name
+		}
+	}
+	return path
+}
+
+// loaderSource is implemented by built-in sources that read state from the Loader
+type loaderSource interface {
+	bind(l *Loader)
+}
+
+// envKeyedSource is implemented by built-in sources keyed by environment variable name alone.
+// Only they are asked which alias of a field is set, so defaults, flags and exec tags never
+// decide the alias
+type envKeyedSource interface {
+	envKeyed()
+}
+
+// defaultSources returns the built-in precedence chain: flags, KEY_FILE secrets, environment,
+// config directories, remote documents, .env files and configuration files (the last one
+// added wins among remotes and among files), exec tags and defaults
+func (l *Loader) defaultSources() []Source {
+	sources := []Source{
+		FlagSource(),
+		FileEnvSource(),
+		EnvSource(),
+	}
+	if len(l.configDirs) > 0 {
+		sources = append(sources, ConfigDirSource(l.configDirs...))
+	}
+	for i := len(l.remotes) - 1; i >= 0; i-- {
+		sources = append(sources, l.remotes[i])
+	}
+	sources = append(sources, DotenvSource())
+	for i := len(l.fileSources) - 1; i >= 0; i-- {
+		sources = append(sources, l.fileSources[i])
+	}
+	return append(sources, ExecSource(), DefaultSource())
+}
+
+// FlagSource returns a Source reading flags explicitly set on the loader's flag set
+func FlagSource() Source {
+	return &flagSource{}
+}
+
+type flagSource struct {
+	l *Loader
+}
+
+func (s *flagSource) bind(l *Loader) { s.l = l }
+
+func (s *flagSource) Name() string { return "flag" }
+
+func (s *flagSource) Lookup(key Key) (string, bool, error) {
+	if key.Flag == "" {
+		return "", false, nil
+	}
+	value, ok := s.l.getFlagValue(key.Flag)
+	return value, ok, nil
+}
+
+// EnvSource returns a Source reading the process environment, or the one injected with WithLookupEnv or WithEnviron
+func EnvSource() Source {
+	return &envSource{}
+}
+
+type envSource struct {
+	l *Loader
+}
+
+func (s *envSource) bind(l *Loader) { s.l = l }
+
+func (s *envSource) envKeyed() {}
+
+func (s *envSource) Name() string { return "env" }
+
+func (s *envSource) Lookup(key Key) (string, bool, error) {
+	if key.Env == "" {
+		return "", false, nil
+	}
+	value, ok := s.l.lookupEnv(key.Env)
+	return value, ok, nil
+}
+
+func (s *envSource) Keys() []string {
+	if s.l.environ == nil {
+		return nil
+	}
+	environ := s.l.environ()
+	keys := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			keys = append(keys, kv[:i])
+		}
+	}
+	return keys
+}
+
+// FileEnvSource returns a Source implementing the Docker secrets convention: when KEY_FILE
+// is set in the environment or a .env file, the trimmed contents of the file it names are the
+// value of KEY. Setting both KEY and KEY_FILE is an error. It must come before EnvSource in
+// the chain to detect that. KEY_FILE is left alone when another field of the struct uses it
+func FileEnvSource() Source {
+	return &fileEnvSource{}
+}
+
+type fileEnvSource struct {
+	l *Loader
+}
+
+func (s *fileEnvSource) bind(l *Loader) { s.l = l }
+
+func (s *fileEnvSource) envKeyed() {}
+
+func (s *fileEnvSource) Name() string { return "_FILE" }
+
+func (s *fileEnvSource) Lookup(key Key) (string, bool, error) {
+	if key.Env == "" || !s.l.fileSuffixEnabled(key.Tag) {
+		return "", false, nil
+	}
+	fileKey := key.Env + "_FILE"
+	if s.l.envKeys[fileKey] {
+		// KEY_FILE is a field of its own, e.g. CertFile next to Cert
+		return "", false, nil
+	}
+	path, ok := s.l.getenv(fileKey)
+	if !ok || path == "" {
+		return "", false, nil
+	}
+	if _, ok := s.l.getenv(key.Env); ok {
+		return "", false, fmt.Errorf("both %s and %s are set", key.Env, fileKey)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", fileKey, err)
+	}
+	return strings.TrimSpace(string(data)), true, nil
+}
+
+// DotenvSource returns a Source reading the .env files configured on the loader
+func DotenvSource() Source {
+	return &dotenvSource{}
+}
+
+type dotenvSource struct {
+	l *Loader
+}
+
+func (s *dotenvSource) bind(l *Loader) { s.l = l }
+
+func (s *dotenvSource) envKeyed() {}
+
+func (s *dotenvSource) Name() string { return "dotenv" }
+
+func (s *dotenvSource) Prepare() error {
+	return s.l.loadEnvFiles()
+}
+
+func (s *dotenvSource) Lookup(key Key) (string, bool, error) {
+	if key.Env == "" {
+		return "", false, nil
+	}
+	value, ok := s.l.dotenv[key.Env]
+	return value, ok, nil
+}
+
+func (s *dotenvSource) Keys() []string {
+	keys := make([]string, 0, len(s.l.dotenv))
+	for key := range s.l.dotenv {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// DefaultSource returns a Source reading the default struct tag, expanding
+// ${VAR} references against the environment and .env files
+func DefaultSource() Source {
+	return &defaultSource{}
+}
+
+type defaultSource struct {
+	l *Loader
+}
+
+func (s *defaultSource) bind(l *Loader) { s.l = l }
+
+func (s *defaultSource) Name() string { return "default" }
+
+func (s *defaultSource) Lookup(key Key) (string, bool, error) {
+	value, err := expandVars(key.Tag.Get("default"), func(name string) (string, bool, error) {
+		value, ok := s.l.getenv(name)
+		return value, ok, nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return value, value != "", nil
+}
+
+// MapSource returns a Source reading values keyed by environment variable name from values
+func MapSource(name string, values map[string]string) Source {
+	return mapSource{name: name, values: values}
+}
+
+type mapSource struct {
+	name   string
+	values map[string]string
+}
+
+func (s mapSource) envKeyed() {}
+
+func (s mapSource) Name() string { return s.name }
+
+func (s mapSource) Lookup(key Key) (string, bool, error) {
+	if key.Env == "" {
+		return "", false, nil
+	}
+	value, ok := s.values[key.Env]
+	return value, ok, nil
+}
+
+func (s mapSource) Keys() []string {
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	return keys
+}
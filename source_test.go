@@ -0,0 +1,416 @@
+package enfl
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type sourceTestConfig struct {
+	Host string `env:"SRC_TEST_HOST" flag:"host" default:"localhost"`
+	Port int    `env:"SRC_TEST_PORT" flag:"port" default:"8080"`
+}
+
+func TestSourcePrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  func() []Source
+		expected sourceTestConfig
+	}{
+		{
+			name:     "Default Chain",
+			sources:  func() []Source { return nil },
+			expected: sourceTestConfig{Host: "env-host", Port: 8080},
+		},
+		{
+			name: "Map Before Env",
+			sources: func() []Source {
+				return []Source{
+					MapSource("overrides", map[string]string{"SRC_TEST_HOST": "map-host", "SRC_TEST_PORT": "9000"}),
+					EnvSource(),
+					DefaultSource(),
+				}
+			},
+			expected: sourceTestConfig{Host: "map-host", Port: 9000},
+		},
+		{
+			name: "Env Before Map",
+			sources: func() []Source {
+				return []Source{
+					EnvSource(),
+					MapSource("overrides", map[string]string{"SRC_TEST_HOST": "map-host", "SRC_TEST_PORT": "9000"}),
+					DefaultSource(),
+				}
+			},
+			expected: sourceTestConfig{Host: "env-host", Port: 9000},
+		},
+		{
+			name:     "Defaults Only",
+			sources:  func() []Source { return []Source{DefaultSource()} },
+			expected: sourceTestConfig{Host: "localhost", Port: 8080},
+		},
+	}
+
+	t.Setenv("SRC_TEST_HOST", "env-host")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option{
+				WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)),
+				WithAutoLoadEnv(false),
+			}
+			if sources := tt.sources(); sources != nil {
+				opts = append(opts, WithSources(sources...))
+			}
+
+			var cfg sourceTestConfig
+			if err := NewLoader(opts...).Load(&cfg); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg != tt.expected {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFlagSourceOverridesEnv(t *testing.T) {
+	t.Setenv("SRC_TEST_PORT", "9000")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	l := NewLoader(WithFlagSet(fs), WithAutoLoadEnv(false))
+
+	var cfg sourceTestConfig
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := fs.Parse([]string{"-port=7000"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != 7000 {
+		t.Errorf("Port = %d, want 7000", cfg.Port)
+	}
+}
+
+func TestInjectedEnvironment(t *testing.T) {
+	type config struct {
+		Host string `env:"HOST" default:"localhost"`
+		Port int    `env:"PORT" default:"8080"`
+	}
+
+	tests := []struct {
+		name     string
+		opt      Option
+		expected config
+	}{
+		{
+			name:     "Environ",
+			opt:      WithEnviron([]string{"HOST=example.com", "PORT=9000"}),
+			expected: config{Host: "example.com", Port: 9000},
+		},
+		{
+			name:     "Map Lookup",
+			opt:      WithLookupEnv(MapLookupEnv(map[string]string{"PORT": "7000"})),
+			expected: config{Host: "localhost", Port: 7000},
+		},
+		{
+			name:     "Empty Environ",
+			opt:      WithEnviron(nil),
+			expected: config{Host: "localhost", Port: 8080},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := NewLoader(
+				WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)),
+				WithAutoLoadEnv(false),
+				tt.opt,
+			)
+
+			var cfg config
+			if err := l.Load(&cfg); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg != tt.expected {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEmptyValues(t *testing.T) {
+	type config struct {
+		Proxy   string `env:"PROXY" default:"http://proxy:3128"`
+		Retries int    `env:"RETRIES" default:"3"`
+		NoProxy string `env:"NO_PROXY" default:"localhost" allowempty:"true"`
+	}
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("PROXY=\nRETRIES=\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected config
+	}{
+		{
+			name:     "Empty Falls Through",
+			opts:     []Option{WithEnviron([]string{"PROXY=", "RETRIES=", "NO_PROXY="})},
+			expected: config{Proxy: "http://proxy:3128", Retries: 3, NoProxy: ""},
+		},
+		{
+			name:     "Empty Is Set",
+			opts:     []Option{WithEnviron([]string{"PROXY=", "RETRIES=", "NO_PROXY="}), WithEmptyIsSet(true)},
+			expected: config{Proxy: "", Retries: 0, NoProxy: ""},
+		},
+		{
+			name:     "Unset Uses Default",
+			opts:     []Option{WithEnviron(nil), WithEmptyIsSet(true)},
+			expected: config{Proxy: "http://proxy:3128", Retries: 3, NoProxy: "localhost"},
+		},
+		{
+			name:     "Dotenv Empty Line",
+			opts:     []Option{WithEnviron(nil), WithEnvFiles(envFile), WithEmptyIsSet(true)},
+			expected: config{Proxy: "", Retries: 0, NoProxy: "localhost"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{
+				WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)),
+				WithAutoLoadEnv(false),
+			}, tt.opts...)
+
+			var cfg config
+			if err := NewLoader(opts...).Load(&cfg); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg != tt.expected {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFileEnvSource(t *testing.T) {
+	type config struct {
+		Password string `env:"DB_PASSWORD" default:"none"`
+		Token    string `env:"TOKEN,API_TOKEN"`
+		CertFile string `env:"CERT" file:"false"`
+	}
+
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(dir, ".env")
+	if err := os.WriteFile(envFile, []byte("DB_PASSWORD_FILE="+secret+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected config
+		wantErr  string
+	}{
+		{
+			name:     "Environment",
+			opts:     []Option{WithEnviron([]string{"DB_PASSWORD_FILE=" + secret})},
+			expected: config{Password: "s3cret"},
+		},
+		{
+			name:     "Dotenv",
+			opts:     []Option{WithEnviron(nil), WithEnvFiles(envFile)},
+			expected: config{Password: "s3cret"},
+		},
+		{
+			name:     "Alternative Name",
+			opts:     []Option{WithEnviron([]string{"API_TOKEN_FILE=" + secret})},
+			expected: config{Password: "none", Token: "s3cret"},
+		},
+		{
+			name:     "Tag Opt Out",
+			opts:     []Option{WithEnviron([]string{"CERT_FILE=" + secret})},
+			expected: config{Password: "none"},
+		},
+		{
+			name:     "Disabled",
+			opts:     []Option{WithEnviron([]string{"DB_PASSWORD_FILE=" + secret}), WithFileSuffix(false)},
+			expected: config{Password: "none"},
+		},
+		{
+			name:    "Both Set",
+			opts:    []Option{WithEnviron([]string{"DB_PASSWORD=plain", "DB_PASSWORD_FILE=" + secret})},
+			wantErr: "both DB_PASSWORD and DB_PASSWORD_FILE are set",
+		},
+		{
+			name:    "Missing File",
+			opts:    []Option{WithEnviron([]string{"DB_PASSWORD_FILE=" + filepath.Join(dir, "missing")})},
+			wantErr: "failed to read DB_PASSWORD_FILE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{
+				WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)),
+				WithAutoLoadEnv(false),
+			}, tt.opts...)
+
+			var cfg config
+			err := NewLoader(opts...).Load(&cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg != tt.expected {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFileEnvSourceFieldPair(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "cert.pem")
+	if err := os.WriteFile(secret, []byte("-----BEGIN CERTIFICATE-----\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// CERT_FILE is the variable of CertFile, not a Docker secret for Cert
+	type config struct {
+		Cert     string `env:"CERT"`
+		CertFile string `env:"CERT_FILE"`
+		Server   struct {
+			Key     string
+			KeyFile string
+		}
+	}
+
+	tests := []struct {
+		name     string
+		env      []string
+		expected config
+	}{
+		{
+			name:     "Both Set",
+			env:      []string{"CERT=inline", "CERT_FILE=" + secret},
+			expected: config{Cert: "inline", CertFile: secret},
+		},
+		{
+			name:     "Only File",
+			env:      []string{"CERT_FILE=" + secret, "SERVER_KEY_FILE=" + secret},
+			expected: config{CertFile: secret, Server: struct{ Key, KeyFile string }{KeyFile: secret}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoader(WithFlagSet(flag.NewFlagSet(tt.name, flag.ContinueOnError)), WithAutoLoadEnv(false), WithEnviron(tt.env))
+			var cfg config
+			if err := l.Load(&cfg); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg != tt.expected {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFileEnvSourceOptIn(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secret, []byte("  tok  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg struct {
+		Token string `env:"TOKEN" file:"true"`
+		Other string `env:"OTHER"`
+	}
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron([]string{"TOKEN_FILE=" + secret, "OTHER_FILE=" + secret}),
+		WithFileSuffix(false),
+	)
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Token != "tok" || cfg.Other != "" {
+		t.Errorf("Load() = %+v, want Token=tok and Other empty", cfg)
+	}
+}
+
+func TestAliasesWithDefaults(t *testing.T) {
+	type config struct {
+		Port  int    `env:"PORT,SERVER_PORT" flag:"port" default:"8080"`
+		Token string `env:"TOKEN,API_TOKEN" default:"none"`
+	}
+
+	secret := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("SERVER_PORT=7070\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected config
+	}{
+		{
+			name:     "Default",
+			opts:     []Option{WithEnviron(nil)},
+			expected: config{Port: 8080, Token: "none"},
+		},
+		{
+			name:     "Second Alias From Env",
+			opts:     []Option{WithEnviron([]string{"SERVER_PORT=9090", "API_TOKEN_FILE=" + secret})},
+			expected: config{Port: 9090, Token: "s3cret"},
+		},
+		{
+			name:     "Second Alias From Dotenv",
+			opts:     []Option{WithEnviron(nil), WithEnvFiles(envFile)},
+			expected: config{Port: 7070, Token: "none"},
+		},
+		{
+			name:     "First Alias Wins",
+			opts:     []Option{WithEnviron([]string{"PORT=1", "SERVER_PORT=2"})},
+			expected: config{Port: 1, Token: "none"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)), WithAutoLoadEnv(false)}, tt.opts...)
+			var cfg config
+			if err := NewLoader(opts...).Load(&cfg); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg != tt.expected {
+				t.Errorf("Load() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
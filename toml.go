@@ -0,0 +1,602 @@
+package enfl
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TOMLFileSource returns a Source reading a TOML document from path. Tables follow the
+// nested struct layout and honour `toml` tags, so [database] fills Config.Database and
+// datetime values bind to time.Time fields
+func TOMLFileSource(path string) Source {
+	return &fileSource{path: path, tag: "toml", parse: parseTOML}
+}
+
+// WithTOMLFile adds a TOML file to the precedence chain, below .env files and above defaults
+func WithTOMLFile(path string) Option {
+	return func(l *Loader) {
+		l.fileSources = append(l.fileSources, TOMLFileSource(path))
+	}
+}
+
+// parseTOML reads a TOML document: tables, arrays of tables, dotted and quoted keys, all
+// string forms, integers, floats, booleans, datetimes, arrays and inline tables
+func parseTOML(r io.Reader, file string) (map[string]interface{}, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &tomlParser{
+		file:    file,
+		src:     strings.ReplaceAll(string(data), "\r\n", "\n"),
+		root:    make(map[string]interface{}),
+		defined: make(map[string]bool),
+	}
+	p.current = p.root
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.root, nil
+}
+
+type tomlParser struct {
+	file    string
+	src     string
+	pos     int
+	root    map[string]interface{}
+	current map[string]interface{} // table receiving key/value pairs
+	defined map[string]bool        // tables declared with a [header]
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipBlank(true)
+		if p.pos >= len(p.src) {
+			return nil
+		}
+
+		var err error
+		if p.src[p.pos] == '[' {
+			err = p.parseTableHeader()
+		} else {
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseTableHeader handles [table] and [[array.of.tables]]
+func (p *tomlParser) parseTableHeader() error {
+	start := p.pos
+	isArray := strings.HasPrefix(p.src[p.pos:], "[[")
+	if isArray {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+
+	p.skipBlank(false)
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipBlank(false)
+
+	closing := "]"
+	if isArray {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return p.errorf("expected %q to close table header", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.descend(p.root, keys[:len(keys)-1], start)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	name := strings.Join(keys, ".")
+
+	if isArray {
+		table := make(map[string]interface{})
+		switch existing := parent[last].(type) {
+		case nil:
+			parent[last] = []interface{}{table}
+		case []interface{}:
+			parent[last] = append(existing, table)
+		default:
+			return p.errorAt(start, "key %s is already defined", name)
+		}
+		p.current = table
+		return nil
+	}
+
+	if p.defined[name] {
+		return p.errorAt(start, "table [%s] is defined twice", name)
+	}
+	p.defined[name] = true
+	switch existing := parent[last].(type) {
+	case nil:
+		table := make(map[string]interface{})
+		parent[last] = table
+		p.current = table
+	case map[string]interface{}:
+		// Implicitly created by an earlier [a.b.c] header
+		p.current = existing
+	default:
+		return p.errorAt(start, "key %s is already defined", name)
+	}
+	return nil
+}
+
+// descend walks keys from table, creating intermediate tables; for arrays of tables it uses the last one
+func (p *tomlParser) descend(table map[string]interface{}, keys []string, pos int) (map[string]interface{}, error) {
+	for _, key := range keys {
+		switch next := table[key].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			table[key] = child
+			table = child
+		case map[string]interface{}:
+			table = next
+		case []interface{}:
+			last, ok := lastTable(next)
+			if !ok {
+				return nil, p.errorAt(pos, "key %s is not a table", key)
+			}
+			table = last
+		default:
+			return nil, p.errorAt(pos, "key %s is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+func lastTable(list []interface{}) (map[string]interface{}, bool) {
+	if len(list) == 0 {
+		return nil, false
+	}
+	table, ok := list[len(list)-1].(map[string]interface{})
+	return table, ok
+}
+
+// parseKeyValue parses key = value into table, dotted keys create nested tables
+func (p *tomlParser) parseKeyValue(table map[string]interface{}) error {
+	start := p.pos
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipBlank(false)
+	if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return p.errorf("expected '=' after key %s", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipBlank(false)
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.descend(table, keys[:len(keys)-1], start)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := parent[last]; exists {
+		return p.errorAt(start, "key %s is already defined", strings.Join(keys, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// parseKey reads a possibly dotted key made of bare or quoted parts
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipBlank(false)
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("expected a key")
+		}
+
+		var key string
+		switch c := p.src[p.pos]; {
+		case c == '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case c == '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := p.pos
+			for p.pos < len(p.src) && isTOMLBareKeyChar(p.src[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("invalid character %q in key", c)
+			}
+			key = p.src[start:p.pos]
+		}
+		keys = append(keys, key)
+
+		p.skipBlank(false)
+		if p.pos >= len(p.src) || p.src[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("expected a value")
+	}
+
+	rest := p.src[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return p.parseMultilineBasicString()
+	case rest[0] == '"':
+		return p.parseBasicString()
+	case strings.HasPrefix(rest, "'''"):
+		return p.parseMultilineLiteralString()
+	case rest[0] == '\'':
+		return p.parseLiteralString()
+	case rest[0] == '[':
+		return p.parseArray()
+	case rest[0] == '{':
+		return p.parseInlineTable()
+	case strings.HasPrefix(rest, "true") && !p.bareContinues(4):
+		p.pos += 4
+		return true, nil
+	case strings.HasPrefix(rest, "false") && !p.bareContinues(5):
+		p.pos += 5
+		return false, nil
+	default:
+		return p.parseNumberOrDatetime()
+	}
+}
+
+// bareContinues reports whether the bare token starting at pos continues after n bytes
+func (p *tomlParser) bareContinues(n int) bool {
+	return p.pos+n < len(p.src) && isTOMLBareKeyChar(p.src[p.pos+n])
+}
+
+func (p *tomlParser) parseArray() (interface{}, error) {
+	p.pos++ // [
+	list := []interface{}{}
+	for {
+		p.skipBlank(true)
+		if p.pos < len(p.src) && p.src[p.pos] == ']' {
+			p.pos++
+			return list, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+
+		p.skipBlank(true)
+		switch {
+		case p.pos < len(p.src) && p.src[p.pos] == ',':
+			p.pos++
+		case p.pos < len(p.src) && p.src[p.pos] == ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (interface{}, error) {
+	p.pos++ // {
+	table := make(map[string]interface{})
+	p.skipBlank(false)
+	if p.pos < len(p.src) && p.src[p.pos] == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		switch {
+		case p.pos < len(p.src) && p.src[p.pos] == ',':
+			p.pos++
+		case p.pos < len(p.src) && p.src[p.pos] == '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	start := p.pos
+	p.pos++ // "
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			return "", p.errorAt(start, "unterminated string")
+		}
+		c := p.src[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *tomlParser) parseMultilineBasicString() (string, error) {
+	start := p.pos
+	p.pos += 3
+	p.trimLeadingNewline()
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return "", p.errorAt(start, "unterminated multi-line string")
+		}
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			// Up to two quotes may directly precede the closing delimiter
+			extra := 0
+			for extra < 2 && strings.HasPrefix(p.src[p.pos+3+extra:], `"`) {
+				extra++
+			}
+			b.WriteString(strings.Repeat(`"`, extra))
+			p.pos += 3 + extra
+			return b.String(), nil
+		}
+
+		c := p.src[p.pos]
+		if c != '\\' {
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		// A backslash ending the line trims all following whitespace and newlines
+		if end := strings.TrimLeft(p.src[p.pos+1:], " \t"); strings.HasPrefix(end, "\n") {
+			p.pos = len(p.src) - len(strings.TrimLeft(end, " \t\n"))
+			continue
+		}
+		if err := p.parseEscape(&b); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	start := p.pos
+	end := strings.IndexAny(p.src[p.pos+1:], "'\n")
+	if end < 0 || p.src[p.pos+1+end] != '\'' {
+		return "", p.errorAt(start, "unterminated string")
+	}
+	s := p.src[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+	return s, nil
+}
+
+func (p *tomlParser) parseMultilineLiteralString() (string, error) {
+	start := p.pos
+	p.pos += 3
+	p.trimLeadingNewline()
+	end := strings.Index(p.src[p.pos:], "'''")
+	if end < 0 {
+		return "", p.errorAt(start, "unterminated multi-line string")
+	}
+	// Up to two quotes may directly precede the closing delimiter
+	for extra := 0; extra < 2 && strings.HasPrefix(p.src[p.pos+end+3:], "'"); extra++ {
+		end++
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.pos += end + 3
+	return s, nil
+}
+
+// parseEscape decodes the escape sequence at pos into b
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+	start := p.pos
+	p.pos++ // backslash
+	if p.pos >= len(p.src) {
+		return p.errorAt(start, "invalid escape sequence")
+	}
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return p.errorAt(start, "invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorAt(start, "invalid unicode escape")
+		}
+		b.WriteRune(rune(code))
+		p.pos += size
+	default:
+		return p.errorAt(start, "invalid escape sequence \\%c", c)
+	}
+	return nil
+}
+
+func (p *tomlParser) trimLeadingNewline() {
+	if strings.HasPrefix(p.src[p.pos:], "\n") {
+		p.pos++
+	}
+}
+
+// parseNumberOrDatetime reads integers, floats, inf/nan and the four datetime forms
+func (p *tomlParser) parseNumberOrDatetime() (interface{}, error) {
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte("0123456789abcdefABCDEFxXoOiIlLnNtTzZ_+-.:", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	token := p.src[start:p.pos]
+
+	// A date may be separated from its time by a space
+	if isTOMLDate(token) && p.pos+3 < len(p.src) && p.src[p.pos] == ' ' && isDigit(p.src[p.pos+1]) && isDigit(p.src[p.pos+2]) && p.src[p.pos+3] == ':' {
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789:.+-zZ", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		token = token + "T" + p.src[start+len(token)+1:p.pos]
+	}
+	if token == "" {
+		return nil, p.errorAt(start, "expected a value")
+	}
+
+	if isTOMLDate(token) || (len(token) >= 8 && token[2] == ':' && token[5] == ':') {
+		value, err := parseTOMLDatetime(token)
+		if err != nil {
+			return nil, p.errorAt(start, "invalid datetime %q", token)
+		}
+		return value, nil
+	}
+
+	value, err := parseTOMLNumber(token)
+	if err != nil {
+		return nil, p.errorAt(start, "invalid value %q", token)
+	}
+	return value, nil
+}
+
+func parseTOMLNumber(token string) (interface{}, error) {
+	switch strings.TrimLeft(token, "+-") {
+	case "inf":
+		if strings.HasPrefix(token, "-") {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+
+	if strings.Contains(token, "__") || strings.HasPrefix(token, "_") || strings.HasSuffix(token, "_") {
+		return nil, fmt.Errorf("misplaced underscore")
+	}
+	digits := strings.ReplaceAll(token, "_", "")
+
+	if len(digits) > 2 && digits[0] == '0' && strings.IndexByte("xob", digits[1]) >= 0 {
+		return strconv.ParseInt(digits, 0, 64)
+	}
+	if strings.ContainsAny(digits, ".eE") {
+		return strconv.ParseFloat(digits, 64)
+	}
+	unsigned := strings.TrimLeft(digits, "+-")
+	if len(unsigned) > 1 && unsigned[0] == '0' {
+		return nil, fmt.Errorf("leading zeros are not allowed")
+	}
+	return strconv.ParseInt(digits, 10, 64)
+}
+
+// parseTOMLDatetime parses offset datetimes, local datetimes and local dates into time.Time;
+// local times have no date and are kept as strings
+func parseTOMLDatetime(token string) (interface{}, error) {
+	token = strings.ToUpper(token)
+	if t, err := time.Parse(time.RFC3339Nano, token); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05.999999999", token, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", token, time.Local); err == nil {
+		return t, nil
+	}
+	if _, err := time.Parse("15:04:05.999999999", token); err == nil {
+		return token, nil
+	}
+	return nil, fmt.Errorf("invalid datetime")
+}
+
+func isTOMLDate(token string) bool {
+	return len(token) >= 10 && isDigit(token[0]) && token[4] == '-' && token[7] == '-'
+}
+
+func isTOMLBareKeyChar(c byte) bool {
+	return isNameChar(c) || c == '-'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// skipBlank skips spaces, tabs and comments, and newlines too when newlines is set
+func (p *tomlParser) skipBlank(newlines bool) {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || (newlines && c == '\n'):
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine requires nothing but whitespace or a comment before the next line
+func (p *tomlParser) endOfLine() error {
+	p.skipBlank(false)
+	if p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		return p.errorf("unexpected %q at end of line", p.src[p.pos])
+	}
+	return nil
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return p.errorAt(p.pos, format, args...)
+}
+
+func (p *tomlParser) errorAt(pos int, format string, args ...interface{}) error {
+	line, col := offsetPosition([]byte(p.src), int64(pos))
+	return &ParseError{File: p.file, Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
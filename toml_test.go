@@ -0,0 +1,200 @@
+package enfl
+
+import (
+	"errors"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]interface{}
+	}{
+		{
+			name:  "Tables And Dotted Keys",
+			input: "title = \"app\" # comment\n\n[database]\nhost = \"db\"\npool.size = 10\n\n[server.http]\n\"read-timeout\" = '5s'\n",
+			expected: map[string]interface{}{
+				"title": "app",
+				"database": map[string]interface{}{
+					"host": "db",
+					"pool": map[string]interface{}{"size": int64(10)},
+				},
+				"server": map[string]interface{}{
+					"http": map[string]interface{}{"read-timeout": "5s"},
+				},
+			},
+		},
+		{
+			name:  "Strings",
+			input: "basic = \"tab\\there \\u00e9\"\nliteral = 'C:\\path'\nmulti = \"\"\"\nline one\nline two\"\"\"\ntrimmed = \"\"\"\\\n    one \\\n    two\"\"\"\nraw = '''\nkeep \\n as is'''\n",
+			expected: map[string]interface{}{
+				"basic":   "tab\there é",
+				"literal": `C:\path`,
+				"multi":   "line one\nline two",
+				"trimmed": "one two",
+				"raw":     `keep \n as is`,
+			},
+		},
+		{
+			name:  "Numbers And Booleans",
+			input: "a = +1_000\nb = -17\nc = 0xff\nd = 0o17\ne = 0b101\nf = 3.14\ng = 5e+2\nh = true\ni = false\n",
+			expected: map[string]interface{}{
+				"a": int64(1000),
+				"b": int64(-17),
+				"c": int64(255),
+				"d": int64(15),
+				"e": int64(5),
+				"f": 3.14,
+				"g": 500.0,
+				"h": true,
+				"i": false,
+			},
+		},
+		{
+			name:  "Datetimes",
+			input: "odt = 1979-05-27T07:32:00Z\nspaced = 1979-05-27 00:32:00.5-07:00\nldt = 1979-05-27T07:32:00\nld = 1979-05-27\nlt = 07:32:00\n",
+			expected: map[string]interface{}{
+				"odt":    time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+				"spaced": time.Date(1979, 5, 27, 0, 32, 0, 5e8, time.FixedZone("", -7*60*60)),
+				"ldt":    time.Date(1979, 5, 27, 7, 32, 0, 0, time.Local),
+				"ld":     time.Date(1979, 5, 27, 0, 0, 0, 0, time.Local),
+				"lt":     "07:32:00",
+			},
+		},
+		{
+			name:  "Arrays And Inline Tables",
+			input: "ports = [ 80, 443, ] # trailing comma\nhosts = [\n  \"a\", # first\n  \"b\",\n]\nempty = []\npoint = { x = 1, y.z = 2 }\n",
+			expected: map[string]interface{}{
+				"ports": []interface{}{int64(80), int64(443)},
+				"hosts": []interface{}{"a", "b"},
+				"empty": []interface{}{},
+				"point": map[string]interface{}{
+					"x": int64(1),
+					"y": map[string]interface{}{"z": int64(2)},
+				},
+			},
+		},
+		{
+			name:  "Array Of Tables",
+			input: "[[servers]]\nname = \"one\"\n[servers.tls]\nenabled = true\n\n[[servers]]\nname = \"two\"\n",
+			expected: map[string]interface{}{
+				"servers": []interface{}{
+					map[string]interface{}{
+						"name": "one",
+						"tls":  map[string]interface{}{"enabled": true},
+					},
+					map[string]interface{}{"name": "two"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML(strings.NewReader(tt.input), "config.toml")
+			if err != nil {
+				t.Fatalf("parseTOML() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseTOML() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTOMLSpecialFloats(t *testing.T) {
+	got, err := parseTOML(strings.NewReader("a = inf\nb = -inf\nc = nan\n"), "config.toml")
+	if err != nil {
+		t.Fatalf("parseTOML() error = %v", err)
+	}
+	if !math.IsInf(got["a"].(float64), 1) || !math.IsInf(got["b"].(float64), -1) || !math.IsNaN(got["c"].(float64)) {
+		t.Errorf("parseTOML() = %v, want +Inf, -Inf, NaN", got)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{name: "Duplicate Key", input: "a = 1\nb = 2\na = 3\n", line: 3},
+		{name: "Duplicate Table", input: "[a]\nx = 1\n[b]\n[a]\n", line: 4},
+		{name: "Missing Equals", input: "a = 1\nb 2\n", line: 2},
+		{name: "Missing Value", input: "a =\n", line: 1},
+		{name: "Unterminated String", input: "a = 1\nb = \"open\n", line: 2},
+		{name: "Bad Escape", input: "a = \"\\q\"\n", line: 1},
+		{name: "Leading Zero", input: "a = 1\nb = 012\n", line: 2},
+		{name: "Trailing Garbage", input: "a = 1 2\n", line: 1},
+		{name: "Unclosed Array", input: "a = [1, 2\nb = 3\n", line: 2},
+		{name: "Key Redefined As Table", input: "a = 1\n[a.b]\n", line: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOML(strings.NewReader(tt.input), "config.toml")
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("parseTOML() error = %v, want *ParseError", err)
+			}
+			if perr.Line != tt.line {
+				t.Errorf("parseTOML() error line = %d, want %d (%v)", perr.Line, tt.line, err)
+			}
+		})
+	}
+}
+
+func TestTOMLFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := "port = 9000\nreleased = 2024-03-01T12:00:00Z\nday = 2024-01-02\n\n[server]\nread_timeout = \"5s\"\nhosts = [\"a\", \"b\"]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type config struct {
+		Port     int       `env:"PORT"`
+		Released time.Time `env:"RELEASED"`
+		Started  time.Time `env:"STARTED" default:"2024-01-02"`
+		Day      time.Time `env:"DAY" layout:"2006-01-02"`
+		Server   struct {
+			ReadTimeout time.Duration `env:"READ_TIMEOUT"`
+			Hosts       []string      `env:"HOSTS" toml:"hosts"`
+		} `prefix:"SERVER_"`
+	}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron(nil),
+		WithTOMLFile(path),
+	)
+
+	var cfg config
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Port != 9000 {
+		t.Errorf("Port = %d, want 9000", cfg.Port)
+	}
+	if want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC); !cfg.Released.Equal(want) {
+		t.Errorf("Released = %v, want %v", cfg.Released, want)
+	}
+	if want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local); !cfg.Started.Equal(want) {
+		t.Errorf("Started = %v, want %v", cfg.Started, want)
+	}
+	if want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local); !cfg.Day.Equal(want) {
+		t.Errorf("Day = %v, want %v", cfg.Day, want)
+	}
+	if cfg.Server.ReadTimeout != 5*time.Second || !reflect.DeepEqual(cfg.Server.Hosts, []string{"a", "b"}) {
+		t.Errorf("Server = %+v, want 5s timeout and hosts [a b]", cfg.Server)
+	}
+}
@@ -0,0 +1,243 @@
+package enfl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// treeSource serves values from a parsed configuration document. Nested objects map onto
+// nested structs, so the value at database.host fills Config.Database.Host. Keys match
+// case-insensitively and ignore '_' and '-', letting max_conns, maxConns and MaxConns all
+// reach a MaxConns field. Objects also fill map fields, keeping their keys as written.
+type treeSource struct {
+	tag     string                       // struct tag naming path segments, e.g. json
+	values  map[string]string            // normalized dotted path -> value
+	lists   map[string][]string          // normalized dotted path -> list of scalars
+	objects map[string]map[string]string // normalized dotted path -> scalar children by original key
+}
+
+func newTreeSource(tag string, doc map[string]interface{}) *treeSource {
+	s := &treeSource{
+		tag:     tag,
+		values:  make(map[string]string),
+		lists:   make(map[string][]string),
+		objects: make(map[string]map[string]string),
+	}
+	for key, value := range doc {
+		// Flat formats such as .properties spell nesting as dotted keys
+		parent, name := "", key
+		if i := strings.LastIndexByte(key, '.'); i >= 0 {
+			parent, name = normalizeKey(key[:i]), key[i+1:]
+		}
+		s.flatten(parent, name, value)
+	}
+	return s
+}
+
+// flatten stores value found under key in the object at parent. Scalars and lists of
+// scalars are stored under their dotted path
+func (s *treeSource) flatten(parent, key string, value interface{}) {
+	path := normalizeKey(key)
+	if parent != "" {
+		path = parent + "." + path
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := s.objects[path]; !ok {
+			s.objects[path] = make(map[string]string)
+		}
+		for key, child := range v {
+			s.flatten(path, key, child)
+		}
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, elem := range v {
+			part, ok := formatScalar(elem)
+			if !ok {
+				return // lists of objects have no struct equivalent
+			}
+			parts = append(parts, part)
+		}
+		// Lists are joined once the separator of the field is known, as map values they
+		// are comma separated
+		s.lists[path] = parts
+		s.storeChild(parent, key, strings.Join(parts, ","))
+	default:
+		if str, ok := formatScalar(v); ok {
+			s.store(parent, key, path, str)
+		}
+	}
+}
+
+func (s *treeSource) store(parent, key, path, value string) {
+	s.values[path] = value
+	s.storeChild(parent, key, value)
+}
+
+// storeChild records value under key in the object at parent for map fields
+func (s *treeSource) storeChild(parent, key, value string) {
+	if parent == "" {
+		return
+	}
+	if s.objects[parent] == nil {
+		s.objects[parent] = make(map[string]string)
+	}
+	s.objects[parent][key] = value
+}
+
+func (s *treeSource) lookup(key Key) (string, bool) {
+	if len(key.Path) == 0 {
+		return "", false
+	}
+	segments := key.PathFor(s.tag)
+	if segments == nil {
+		return "", false
+	}
+	for i, segment := range segments {
+		segments[i] = normalizeKey(segment)
+	}
+	path := strings.Join(segments, ".")
+	if value, ok := s.values[path]; ok {
+		return value, ok
+	}
+	if list, ok := s.lists[path]; ok {
+		return joinList(list, key), true
+	}
+
+	// An object fills a map field, written in the syntax its sep and kvsep tags expect with
+	// separators inside keys and values escaped
+	object, ok := s.objects[path]
+	if !ok || len(key.fields) == 0 {
+		return "", false
+	}
+	if !isKeyKind(key, reflect.Map) {
+		return "", false
+	}
+	sep, kvsep := tagValue(key.Tag, "sep", ","), tagValue(key.Tag, "kvsep", ":")
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = escapeSeparators(name, sep, kvsep) + kvsep + escapeSeparators(object[name], sep)
+	}
+	return strings.Join(pairs, sep), true
+}
+
+// joinList joins a list in the syntax of the field's sep tag. Separators inside elements
+// of slice fields are escaped so every element is kept whole
+func joinList(list []string, key Key) string {
+	sep := tagValue(key.Tag, "sep", ",")
+	if !isKeyKind(key, reflect.Slice) {
+		return strings.Join(list, sep)
+	}
+	escaped := make([]string, len(list))
+	for i, elem := range list {
+		escaped[i] = escapeSeparators(elem, sep)
+	}
+	return strings.Join(escaped, sep)
+}
+
+// isKeyKind reports whether the field of key, or the type it points to, is of kind k
+func isKeyKind(key Key, k reflect.Kind) bool {
+	if len(key.fields) == 0 {
+		return false
+	}
+	t := key.fields[len(key.fields)-1].Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == k
+}
+
+func (s *treeSource) keys() []string {
+	keys := make([]string, 0, len(s.values)+len(s.lists))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	for key := range s.lists {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatScalar renders a document scalar the way it would be written in an environment variable
+func formatScalar(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	default:
+		return "", false
+	}
+}
+
+// normalizeKey lowercases key and drops '_' and '-'
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	return strings.NewReplacer("_", "", "-", "").Replace(key)
+}
+
+// fileSource reads a structured configuration file once per Load
+type fileSource struct {
+	path  string
+	tag   string
+	parse func(r io.Reader, file string) (map[string]interface{}, error)
+	fsys  fs.FS // set from WithConfigFS, nil for the OS
+	tree  *treeSource
+}
+
+func (s *fileSource) bind(l *Loader) { s.fsys = l.configFS }
+
+func (s *fileSource) Name() string { return s.path }
+
+func (s *fileSource) Prepare() error {
+	s.tree = nil
+	file, err := openFile(s.fsys, s.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", s.path, err)
+	}
+	defer file.Close()
+
+	doc, err := s.parse(file, s.path)
+	if err != nil {
+		return err
+	}
+	s.tree = newTreeSource(s.tag, doc)
+	return nil
+}
+
+func (s *fileSource) Lookup(key Key) (string, bool, error) {
+	if s.tree == nil {
+		return "", false, nil
+	}
+	value, ok := s.tree.lookup(key)
+	return value, ok, nil
+}
+
+func (s *fileSource) Keys() []string {
+	if s.tree == nil {
+		return nil
+	}
+	return s.tree.keys()
+}
@@ -0,0 +1,638 @@
+package enfl
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// YAMLFileSource returns a Source reading a YAML mapping from path. Mappings follow the
+// nested struct layout and honour `yaml` tags, e.g. "database:\n  host: db" fills
+// Config.Database.Host
+func YAMLFileSource(path string) Source {
+	return &fileSource{path: path, tag: "yaml", parse: parseYAML}
+}
+
+// WithYAMLFile adds a YAML file to the precedence chain, below .env files and above defaults
+func WithYAMLFile(path string) Option {
+	return func(l *Loader) {
+		l.fileSources = append(l.fileSources, YAMLFileSource(path))
+	}
+}
+
+// parseYAML reads a single YAML document whose top level is a mapping. The supported subset
+// covers what configuration files use: block mappings and sequences, flow collections
+// ([a, b] and {a: 1}), plain, quoted and block (| and >) scalars, and comments. Anchors,
+// aliases, tags and multi-document streams are rejected with an error.
+func parseYAML(r io.Reader, file string) (map[string]interface{}, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newYAMLParser(file, string(data))
+	return p.parseDocument()
+}
+
+// yamlLine is a source line split into indentation and content
+type yamlLine struct {
+	num    int    // 1-based line number
+	indent int    // leading spaces
+	text   string // content without indentation, comment and trailing whitespace
+	raw    string // the line as written, used by block scalars
+	tab    bool   // indented with a tab
+}
+
+type yamlParser struct {
+	file  string
+	lines []*yamlLine
+	pos   int
+}
+
+func newYAMLParser(file, src string) *yamlParser {
+	p := &yamlParser{file: file}
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	for i, raw := range strings.Split(src, "\n") {
+		trimmed := strings.TrimLeft(raw, " ")
+		p.lines = append(p.lines, &yamlLine{
+			num:    i + 1,
+			indent: len(raw) - len(trimmed),
+			text:   strings.TrimRight(stripYAMLComment(trimmed), " \t"),
+			raw:    raw,
+			tab:    strings.HasPrefix(trimmed, "\t") && strings.TrimSpace(trimmed) != "",
+		})
+	}
+	return p
+}
+
+func (p *yamlParser) parseDocument() (map[string]interface{}, error) {
+	line, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if line != nil && line.text == "---" {
+		p.pos++
+		if line, err = p.peek(); err != nil {
+			return nil, err
+		}
+	}
+	if line == nil || line.text == "..." {
+		return map[string]interface{}{}, nil
+	}
+
+	node, err := p.parseNode(line)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, p.errorf(line, line.indent+1, "top level must be a mapping")
+	}
+
+	// Only an end marker may follow the document
+	if line, err = p.peek(); err != nil {
+		return nil, err
+	}
+	switch {
+	case line == nil || line.text == "...":
+		return doc, nil
+	case line.text == "---":
+		return nil, p.errorf(line, 1, "multiple documents are not supported")
+	default:
+		return nil, p.errorf(line, line.indent+1, "unexpected indentation")
+	}
+}
+
+// peek returns the next line with content without consuming it, nil at the end
+func (p *yamlParser) peek() (*yamlLine, error) {
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if line.text == "" {
+			continue
+		}
+		if line.tab {
+			return nil, p.errorf(line, line.indent+1, "tabs are not allowed for indentation")
+		}
+		return line, nil
+	}
+	return nil, nil
+}
+
+// parseNode parses the block collection or scalar starting at line
+func (p *yamlParser) parseNode(line *yamlLine) (interface{}, error) {
+	if isYAMLSeqEntry(line.text) {
+		return p.parseSequence(line.indent)
+	}
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return p.parseMapping(line.indent)
+	}
+	p.pos++
+	return p.parseInline(line, line.text, line.indent+1)
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for {
+		line, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if line == nil || line.indent < indent || isYAMLDocMarker(line.text) {
+			return m, nil
+		}
+		if line.indent > indent {
+			return nil, p.errorf(line, line.indent+1, "unexpected indentation")
+		}
+		if isYAMLSeqEntry(line.text) {
+			return nil, p.errorf(line, line.indent+1, "expected a mapping key, found a sequence entry")
+		}
+
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, p.errorf(line, line.indent+1, "expected 'key: value'")
+		}
+		if _, exists := m[key]; exists {
+			return nil, p.errorf(line, line.indent+1, "duplicate key %q", key)
+		}
+		p.pos++
+
+		col := line.indent + len(line.text) - len(rest) + 1
+		value, err := p.parseValue(line, rest, indent, col, true)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	seq := []interface{}{}
+	for {
+		line, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if line == nil || line.indent < indent || isYAMLDocMarker(line.text) {
+			return seq, nil
+		}
+		if line.indent > indent {
+			return nil, p.errorf(line, line.indent+1, "unexpected indentation")
+		}
+		if !isYAMLSeqEntry(line.text) {
+			return seq, nil
+		}
+
+		rest := strings.TrimLeft(line.text[1:], " ")
+		offset := len(line.text) - len(rest)
+		if _, _, isKey := splitYAMLKey(rest); rest != "" && (isKey || isYAMLSeqEntry(rest)) {
+			// "- key: value" and "- - item" open a collection at the column of their content
+			line.indent += offset
+			line.text = rest
+			item, err := p.parseNode(line)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, item)
+			continue
+		}
+
+		p.pos++
+		item, err := p.parseValue(line, rest, indent, indent+offset+1, false)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, item)
+	}
+}
+
+// parseValue parses what follows "key:" or "-", which is either on the same line or an indented block
+func (p *yamlParser) parseValue(line *yamlLine, rest string, indent, col int, inMapping bool) (interface{}, error) {
+	switch {
+	case rest == "":
+		next, err := p.peek()
+		if err != nil || next == nil {
+			return nil, err
+		}
+		if next.indent > indent {
+			return p.parseNode(next)
+		}
+		// A mapping value may be a sequence at the same indentation as its key
+		if inMapping && next.indent == indent && isYAMLSeqEntry(next.text) {
+			return p.parseSequence(indent)
+		}
+		return nil, nil
+	case rest[0] == '|' || rest[0] == '>':
+		return p.parseBlockScalar(line, rest, indent, col)
+	default:
+		return p.parseInline(line, rest, col)
+	}
+}
+
+// parseInline parses a flow collection or scalar written on the line
+func (p *yamlParser) parseInline(line *yamlLine, text string, col int) (interface{}, error) {
+	switch text[0] {
+	case '&', '*':
+		return nil, p.errorf(line, col, "anchors and aliases are not supported")
+	case '!':
+		return nil, p.errorf(line, col, "tags are not supported")
+	case '[', '{':
+		// Flow collections may continue on the following lines
+		for !flowBalanced(text) && p.pos < len(p.lines) {
+			text += " " + strings.TrimSpace(p.lines[p.pos].text)
+			p.pos++
+		}
+		f := &yamlFlow{p: p, line: line, col: col, s: text}
+		value, err := f.parse()
+		if err != nil {
+			return nil, err
+		}
+		if f.skipSpaces(); f.i < len(f.s) {
+			return nil, f.errorf("unexpected %q after flow collection", f.s[f.i:])
+		}
+		return value, nil
+	case '"', '\'':
+		end := closingQuote(text)
+		if end < 0 {
+			return nil, p.errorf(line, col, "unterminated quoted scalar")
+		}
+		if end != len(text)-1 {
+			return nil, p.errorf(line, col+end+1, "unexpected %q after quoted scalar", text[end+1:])
+		}
+		value, err := unquoteYAML(text)
+		if err != nil {
+			return nil, p.errorf(line, col, "%v", err)
+		}
+		return value, nil
+	default:
+		return plainYAMLScalar(text), nil
+	}
+}
+
+// parseBlockScalar reads a literal (|) or folded (>) scalar from the lines indented below indent
+func (p *yamlParser) parseBlockScalar(line *yamlLine, header string, indent, col int) (interface{}, error) {
+	style := header[0]
+	chomp := byte(0)
+	blockIndent := 0
+	for i := 1; i < len(header); i++ {
+		switch c := header[i]; {
+		case (c == '-' || c == '+') && chomp == 0:
+			chomp = c
+		case c >= '1' && c <= '9' && blockIndent == 0:
+			blockIndent = indent + int(c-'0')
+		default:
+			return nil, p.errorf(line, col+i, "invalid block scalar header %q", header)
+		}
+	}
+
+	var body []string
+	for ; p.pos < len(p.lines); p.pos++ {
+		next := p.lines[p.pos]
+		if strings.TrimSpace(next.raw) == "" {
+			body = append(body, "")
+			continue
+		}
+		if blockIndent == 0 {
+			blockIndent = next.indent
+		}
+		if next.indent < blockIndent || next.indent <= indent {
+			break
+		}
+		body = append(body, next.raw[blockIndent:])
+	}
+
+	// Separate trailing blank lines, they only matter for the keep (+) indicator
+	trailing := 0
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		trailing++
+	}
+
+	var b strings.Builder
+	for i, text := range body {
+		if style == '|' {
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(text)
+			continue
+		}
+		// Folded: single line breaks become spaces, empty lines become line breaks
+		if text == "" {
+			b.WriteByte('\n')
+			continue
+		}
+		if i > 0 && body[i-1] != "" {
+			if isSpace(rune(text[0])) || isSpace(rune(body[i-1][0])) {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(text)
+	}
+
+	value := b.String()
+	switch {
+	case chomp == '-' || len(body) == 0 && chomp != '+':
+	case chomp == '+':
+		value += strings.Repeat("\n", trailing+1)
+	default:
+		value += "\n"
+	}
+	return value, nil
+}
+
+func (p *yamlParser) errorf(line *yamlLine, col int, format string, args ...interface{}) error {
+	return &ParseError{File: p.file, Line: line.num, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// yamlFlow parses a flow collection such as [a, {b: 1}]
+type yamlFlow struct {
+	p    *yamlParser
+	line *yamlLine
+	col  int
+	s    string
+	i    int
+}
+
+func (f *yamlFlow) parse() (interface{}, error) {
+	f.skipSpaces()
+	if f.i >= len(f.s) {
+		return nil, f.errorf("unexpected end of flow collection")
+	}
+
+	switch f.s[f.i] {
+	case '[':
+		f.i++
+		seq := []interface{}{}
+		for {
+			if f.skipSpaces(); f.i < len(f.s) && f.s[f.i] == ']' {
+				f.i++
+				return seq, nil
+			}
+			item, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, item)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.i++
+		m := make(map[string]interface{})
+		for {
+			if f.skipSpaces(); f.i < len(f.s) && f.s[f.i] == '}' {
+				f.i++
+				return m, nil
+			}
+			keyNode, err := f.scalar(true)
+			if err != nil {
+				return nil, err
+			}
+			key, _ := formatScalar(keyNode)
+			if _, exists := m[key]; exists {
+				return nil, f.errorf("duplicate key %q", key)
+			}
+
+			var value interface{}
+			if f.skipSpaces(); f.i < len(f.s) && f.s[f.i] == ':' {
+				f.i++
+				if value, err = f.parse(); err != nil {
+					return nil, err
+				}
+			}
+			m[key] = value
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return f.scalar(false)
+	}
+}
+
+// separator consumes a ',' or, without consuming it, the closing bracket
+func (f *yamlFlow) separator(closing byte) error {
+	f.skipSpaces()
+	switch {
+	case f.i < len(f.s) && f.s[f.i] == ',':
+		f.i++
+		return nil
+	case f.i < len(f.s) && f.s[f.i] == closing:
+		return nil
+	default:
+		return f.errorf("expected ',' or '%c'", closing)
+	}
+}
+
+// scalar reads a quoted or plain scalar; plain keys also stop at ':'
+func (f *yamlFlow) scalar(isKey bool) (interface{}, error) {
+	f.skipSpaces()
+	rest := f.s[f.i:]
+	if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+		end := closingQuote(rest)
+		if end < 0 {
+			return nil, f.errorf("unterminated quoted scalar")
+		}
+		value, err := unquoteYAML(rest[:end+1])
+		if err != nil {
+			return nil, f.errorf("%v", err)
+		}
+		f.i += end + 1
+		return value, nil
+	}
+
+	start := f.i
+	for ; f.i < len(f.s); f.i++ {
+		c := f.s[f.i]
+		if c == ',' || c == ']' || c == '}' || c == '[' || c == '{' {
+			break
+		}
+		if c == ':' && (isKey || f.i+1 == len(f.s) || f.s[f.i+1] == ' ') {
+			break
+		}
+	}
+	text := strings.TrimSpace(f.s[start:f.i])
+	if text != "" && (text[0] == '&' || text[0] == '*' || text[0] == '!') {
+		return nil, f.errorf("anchors, aliases and tags are not supported")
+	}
+	return plainYAMLScalar(text), nil
+}
+
+func (f *yamlFlow) skipSpaces() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *yamlFlow) errorf(format string, args ...interface{}) error {
+	return f.p.errorf(f.line, f.col+f.i, format, args...)
+}
+
+// splitYAMLKey splits "key: value" into key and value, reporting whether text is a mapping entry
+func splitYAMLKey(text string) (string, string, bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' || text[0] == '#' {
+		return "", "", false
+	}
+
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 {
+			return "", "", false
+		}
+		after := text[end+1:]
+		if !strings.HasPrefix(after, ":") || (len(after) > 1 && after[1] != ' ') {
+			return "", "", false
+		}
+		key, err := unquoteYAML(text[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(after[1:]), true
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+func isYAMLSeqEntry(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func isYAMLDocMarker(text string) bool {
+	return text == "---" || text == "..."
+}
+
+// plainYAMLScalar resolves the null forms of a plain scalar, everything else stays a string
+func plainYAMLScalar(text string) interface{} {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	}
+	return text
+}
+
+// stripYAMLComment removes a '#' comment that starts the line or follows whitespace outside quotes
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if (c == '\\' && quote == '"') || (c == '\'' && quote == '\'' && i+1 < len(text) && text[i+1] == '\'') {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" [{,:-", text[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// closingQuote returns the index of the quote closing the scalar that starts text, or -1
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unquoteYAML decodes a single or double quoted scalar including its quotes
+func unquoteYAML(text string) (string, error) {
+	inner := text[1 : len(text)-1]
+	if text[0] == '\'' {
+		return strings.ReplaceAll(inner, "''", "'"), nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] != '\\' {
+			b.WriteByte(inner[i])
+			continue
+		}
+		i++
+		if i >= len(inner) {
+			return "", fmt.Errorf("invalid escape at end of quoted scalar")
+		}
+		switch c := inner[i]; c {
+		case '0':
+			b.WriteByte(0)
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'v':
+			b.WriteByte('\v')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case 'e':
+			b.WriteByte(0x1b)
+		case ' ', '"', '/', '\\':
+			b.WriteByte(c)
+		case 'x', 'u', 'U':
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+			if i+1+size > len(inner) {
+				return "", fmt.Errorf("invalid \\%c escape", c)
+			}
+			code, err := strconv.ParseUint(inner[i+1:i+1+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid \\%c escape", c)
+			}
+			b.WriteRune(rune(code))
+			i += size
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", c)
+		}
+	}
+	return b.String(), nil
+}
+
+// flowBalanced reports whether every bracket opened in text outside quotes is closed
+func flowBalanced(text string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" [{,:", text[i-1]) >= 0):
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
@@ -0,0 +1,163 @@
+package enfl
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "Empty Document",
+			input:    "# only a comment\n---\n",
+			expected: map[string]interface{}{},
+		},
+		{
+			name:  "Nested Mappings",
+			input: "name: app # trailing comment\ndatabase:\n  host: db.internal\n  port: 5432\n  options:\n    ssl: true\nempty:\n",
+			expected: map[string]interface{}{
+				"name": "app",
+				"database": map[string]interface{}{
+					"host":    "db.internal",
+					"port":    "5432",
+					"options": map[string]interface{}{"ssl": "true"},
+				},
+				"empty": nil,
+			},
+		},
+		{
+			name:  "Sequences",
+			input: "tags:\n  - a\n  - b\nsame_indent:\n- x\n- y\nservers:\n  - host: one\n    port: 1\n  - host: two\nnested:\n  - - 1\n    - 2\n",
+			expected: map[string]interface{}{
+				"tags":        []interface{}{"a", "b"},
+				"same_indent": []interface{}{"x", "y"},
+				"servers": []interface{}{
+					map[string]interface{}{"host": "one", "port": "1"},
+					map[string]interface{}{"host": "two"},
+				},
+				"nested": []interface{}{[]interface{}{"1", "2"}},
+			},
+		},
+		{
+			name:  "Flow Collections",
+			input: "tags: [a, 'b, c', \"d\"]\nlabels: {env: prod, url: http://x:80/y}\nmulti: [\n  1,\n  2\n]\nnone: []\n",
+			expected: map[string]interface{}{
+				"tags":   []interface{}{"a", "b, c", "d"},
+				"labels": map[string]interface{}{"env": "prod", "url": "http://x:80/y"},
+				"multi":  []interface{}{"1", "2"},
+				"none":   []interface{}{},
+			},
+		},
+		{
+			name:  "Quoted Scalars",
+			input: "single: 'it''s # not a comment'\ndouble: \"tab\\there \\u00e9\"\n\"quoted key\": ~\nurl: http://example.com/#frag\n",
+			expected: map[string]interface{}{
+				"single":     "it's # not a comment",
+				"double":     "tab\there é",
+				"quoted key": nil,
+				"url":        "http://example.com/#frag",
+			},
+		},
+		{
+			name:  "Block Scalars",
+			input: "cert: |\n  -----BEGIN-----\n  # kept\n  abc\n  -----END-----\nfolded: >-\n  one\n  two\n\n  three\nkeep: |+\n  x\n\nnext: 1\n",
+			expected: map[string]interface{}{
+				"cert":   "-----BEGIN-----\n# kept\nabc\n-----END-----\n",
+				"folded": "one two\nthree",
+				"keep":   "x\n\n",
+				"next":   "1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML(strings.NewReader(tt.input), "config.yaml")
+			if err != nil {
+				t.Fatalf("parseYAML() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseYAML() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{name: "Bad Indentation", input: "a: 1\n  b: 2\n", line: 2},
+		{name: "Tab Indentation", input: "a:\n\tb: 1\n", line: 2},
+		{name: "Duplicate Key", input: "a: 1\nb: 2\na: 3\n", line: 3},
+		{name: "Not A Mapping", input: "- a\n- b\n", line: 1},
+		{name: "Missing Colon", input: "a: 1\njust text\n", line: 2},
+		{name: "Unterminated Quote", input: "a: \"open\n", line: 1},
+		{name: "Unclosed Flow", input: "a: [1, 2\nb: 3\n", line: 1},
+		{name: "Alias", input: "a: &x 1\nb: *x\n", line: 1},
+		{name: "Multiple Documents", input: "a: 1\n---\nb: 2\n", line: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML(strings.NewReader(tt.input), "config.yaml")
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("parseYAML() error = %v, want *ParseError", err)
+			}
+			if perr.Line != tt.line {
+				t.Errorf("parseYAML() error line = %d, want %d (%v)", perr.Line, tt.line, err)
+			}
+		})
+	}
+}
+
+func TestYAMLFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "port: 9000\nserver:\n  read_timeout: 5s\n  hosts: [a, b]\n  zones: [a, b]\n  pairs: ['x,y', z]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type config struct {
+		Port   int `env:"PORT"`
+		Server struct {
+			ReadTimeout time.Duration `env:"READ_TIMEOUT"`
+			Hosts       []string      `env:"HOSTS" yaml:"hosts"`
+			Zones       []string      `env:"ZONES" sep:";"`
+			Pairs       []string      `env:"PAIRS"`
+			Name        string        `env:"NAME" default:"web"`
+		} `prefix:"SERVER_"`
+	}
+
+	l := NewLoader(
+		WithFlagSet(flag.NewFlagSet("test", flag.ContinueOnError)),
+		WithAutoLoadEnv(false),
+		WithEnviron([]string{"SERVER_READ_TIMEOUT=10s"}),
+		WithYAMLFile(path),
+	)
+
+	var cfg config
+	if err := l.Load(&cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != 9000 || cfg.Server.ReadTimeout != 10*time.Second || !reflect.DeepEqual(cfg.Server.Hosts, []string{"a", "b"}) || cfg.Server.Name != "web" {
+		t.Errorf("Load() = %+v", cfg)
+	}
+	// Lists keep their elements whatever the separator of the field
+	if !reflect.DeepEqual(cfg.Server.Zones, []string{"a", "b"}) || !reflect.DeepEqual(cfg.Server.Pairs, []string{"x,y", "z"}) {
+		t.Errorf("Zones = %q, Pairs = %q, want [a b] and [x,y z]", cfg.Server.Zones, cfg.Server.Pairs)
+	}
+}